@@ -0,0 +1,46 @@
+// Command mockoidc is a minimal OpenID Connect provider for local testing of
+// single sign-on. It signs every user in as the configured identity without
+// asking for credentials, and supports exactly what GoFamTree uses: discovery,
+// the authorization-code flow with PKCE (S256), RS256 ID tokens and JWKS.
+//
+//	go run ./cmd/mockoidc -addr :9000 -client-id gofamtree -client-secret secret
+//
+// Then start the API with OIDC_ISSUER=http://localhost:9000,
+// OIDC_CLIENT_ID=gofamtree, OIDC_CLIENT_SECRET=secret and
+// OIDC_REDIRECT_URL=http://localhost:8080/admin/oidc/callback.
+package main
+
+import (
+	"flag"
+	"gofamtree/cmd/mockoidc/provider"
+	"log"
+	"net/http"
+)
+
+func main() {
+	addr := flag.String("addr", ":9000", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9000", "issuer URL (must match how clients reach this server)")
+	clientID := flag.String("client-id", "gofamtree", "accepted client ID")
+	clientSecret := flag.String("client-secret", "secret", "accepted client secret (empty for a public client)")
+	subject := flag.String("sub", "mock-user-1", "subject of the signed-in user")
+	email := flag.String("email", "mock.user@example.com", "email of the signed-in user")
+	username := flag.String("username", "mockuser", "preferred_username of the signed-in user")
+	name := flag.String("name", "Mock User", "display name of the signed-in user")
+	flag.Parse()
+
+	p, err := provider.New(provider.Config{
+		Issuer:       *issuer,
+		ClientID:     *clientID,
+		ClientSecret: *clientSecret,
+		Subject:      *subject,
+		Email:        *email,
+		Username:     *username,
+		Name:         *name,
+	})
+	if err != nil {
+		log.Fatal("Failed to generate signing key:", err)
+	}
+
+	log.Printf("Mock OIDC provider %s listening on %s (client %s, user %s)", p.Issuer, *addr, p.ClientID, p.Subject)
+	log.Fatal(http.ListenAndServe(*addr, p.Handler()))
+}
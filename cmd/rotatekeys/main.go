@@ -0,0 +1,168 @@
+// Command rotatekeys re-encrypts the encrypted person columns and audit log
+// snapshots with the active key, the first entry of ENCRYPTION_KEYS. It also
+// encrypts values written before encryption was enabled.
+//
+// To rotate, put the new key first and keep the old one after it:
+//
+//	ENCRYPTION_KEYS=k2:<new key>,k1:<old key> go run ./cmd/rotatekeys
+//
+// Once it reports no remaining rows, the old key can be removed. The command is
+// safe to interrupt and run again.
+package main
+
+import (
+	"flag"
+	"gofamtree/config"
+	"gofamtree/models"
+	"gofamtree/utils"
+	"log"
+)
+
+// personColumns are the persons columns tagged serializer:encrypted
+var personColumns = []string{"contact", "description", "cause_of_death"}
+
+type personRow struct {
+	ID           uint
+	Contact      *string
+	Description  *string
+	CauseOfDeath *string
+}
+
+type auditRow struct {
+	ID     uint
+	Before models.JSON
+	After  models.JSON
+}
+
+func main() {
+	batchSize := flag.Int("batch", 500, "rows loaded per query")
+	dryRun := flag.Bool("dry-run", false, "count the rows that need re-encryption without changing them")
+	flag.Parse()
+
+	config.InitDB()
+	config.InitEncryption()
+	utils.InitFieldEncryption()
+	if utils.FieldKeys == nil {
+		log.Fatal("ENCRYPTION_KEYS is not set - nothing to encrypt with")
+	}
+
+	persons, err := rotatePersons(*batchSize, *dryRun)
+	if err != nil {
+		log.Fatal("Failed to re-encrypt persons:", err)
+	}
+	entries, err := rotateAuditLog(*batchSize, *dryRun)
+	if err != nil {
+		log.Fatal("Failed to re-encrypt audit log:", err)
+	}
+
+	verb := "Re-encrypted"
+	if *dryRun {
+		verb = "Would re-encrypt"
+	}
+	log.Printf("%s %d person(s) and %d audit log entries with key %q", verb, persons, entries, utils.FieldKeys.Active)
+}
+
+// reencrypt decrypts a stored value with whichever key wrote it and encrypts it
+// again with the active key
+func reencrypt(value, column string) (string, error) {
+	if !utils.FieldNeedsReencryption(value) {
+		return value, nil
+	}
+	plaintext, err := utils.DecryptField(value, column)
+	if err != nil {
+		return "", err
+	}
+	return utils.EncryptField(plaintext, column)
+}
+
+func rotatePersons(batchSize int, dryRun bool) (int, error) {
+	changed := 0
+	var lastID uint
+	for {
+		var rows []personRow
+		if err := config.DB.Table("persons").Select("id", "contact", "description", "cause_of_death").
+			Where("id > ?", lastID).Order("id").Limit(batchSize).Find(&rows).Error; err != nil {
+			return changed, err
+		}
+		if len(rows) == 0 {
+			return changed, nil
+		}
+		lastID = rows[len(rows)-1].ID
+
+		for _, row := range rows {
+			updates := map[string]interface{}{}
+			for i, value := range []*string{row.Contact, row.Description, row.CauseOfDeath} {
+				if value == nil || !utils.FieldNeedsReencryption(*value) {
+					continue
+				}
+				encrypted, err := reencrypt(*value, personColumns[i])
+				if err != nil {
+					return changed, err
+				}
+				updates[personColumns[i]] = encrypted
+			}
+			if len(updates) == 0 {
+				continue
+			}
+
+			changed++
+			if dryRun {
+				continue
+			}
+			// Raw column updates, so the serializer does not encrypt a second time
+			if err := config.DB.Table("persons").Where("id = ?", row.ID).Updates(updates).Error; err != nil {
+				return changed, err
+			}
+		}
+	}
+}
+
+func rotateAuditLog(batchSize int, dryRun bool) (int, error) {
+	changed := 0
+	var lastID uint
+	for {
+		var rows []auditRow
+		if err := config.DB.Table("audit_log").Select("id", "before", "after").
+			Where("id > ?", lastID).Order("id").Limit(batchSize).Find(&rows).Error; err != nil {
+			return changed, err
+		}
+		if len(rows) == 0 {
+			return changed, nil
+		}
+		lastID = rows[len(rows)-1].ID
+
+		for _, row := range rows {
+			updates := map[string]interface{}{}
+			for column, snapshot := range map[string]models.JSON{"before": row.Before, "after": row.After} {
+				if !snapshotNeedsReencryption(snapshot) {
+					continue
+				}
+				rotated, err := models.TransformSnapshot(snapshot, reencrypt)
+				if err != nil {
+					return changed, err
+				}
+				updates[column] = rotated
+			}
+			if len(updates) == 0 {
+				continue
+			}
+
+			changed++
+			if dryRun {
+				continue
+			}
+			if err := config.DB.Table("audit_log").Where("id = ?", row.ID).Updates(updates).Error; err != nil {
+				return changed, err
+			}
+		}
+	}
+}
+
+func snapshotNeedsReencryption(snapshot models.JSON) bool {
+	needed := false
+	models.TransformSnapshot(snapshot, func(value, column string) (string, error) {
+		needed = needed || utils.FieldNeedsReencryption(value)
+		return value, nil
+	})
+	return needed
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"crypto/rand"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// minJWTSecretLength is the shortest JWT_SECRET accepted, in bytes
+const minJWTSecretLength = 32
+
+// placeholderSecrets are sample values that must never sign real tokens
+var placeholderSecrets = []string{"change-me", "changeme", "secret", "your-secret-key", "jwt-secret"}
+
+var (
+	JWTSecret []byte
+	TokenTTL  time.Duration // lifetime of access tokens
+
+	// Login sessions are renewed with rotating refresh tokens
+	RefreshTokenTTL time.Duration // a session idle for longer than this must log in again
+	SessionMaxAge   time.Duration // no session outlives this, however often it is refreshed
+
+	// Issuer name shown in authenticator apps
+	TOTPIssuer string
+
+	// Trust X-Forwarded-For / X-Real-IP when running behind a reverse proxy
+	TrustProxyHeaders bool
+	TrustedProxyHops  int // proxies in front of the API, each appending to X-Forwarded-For
+
+	// Brute-force protection for AdminLogin
+	LoginMaxFailures   int           // failures per username before the account is locked
+	LoginMaxIPFailures int           // failures per client IP before the IP is blocked
+	LoginFailureWindow time.Duration // failures older than this are forgotten
+	LoginLockout       time.Duration // how long a locked account or blocked IP waits
+	LoginMaxDelay      time.Duration // cap for the progressive delay between attempts
+
+	// How long a registration invite link stays valid
+	RegistrationInviteTTL time.Duration
+)
+
+func InitAuth() {
+	// Secret used to sign bearer tokens issued by AdminLogin
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		log.Println("WARNING: JWT_SECRET not set, using a random secret - tokens will not survive a restart")
+		JWTSecret = make([]byte, 32)
+		if _, err := rand.Read(JWTSecret); err != nil {
+			log.Fatal("Failed to generate JWT secret:", err)
+		}
+	} else {
+		for _, placeholder := range placeholderSecrets {
+			if strings.EqualFold(secret, placeholder) {
+				log.Fatal("JWT_SECRET is a placeholder value; set a random secret, e.g. openssl rand -base64 32")
+			}
+		}
+		if len(secret) < minJWTSecretLength {
+			log.Fatalf("JWT_SECRET must be at least %d bytes", minJWTSecretLength)
+		}
+		JWTSecret = []byte(secret)
+	}
+
+	TokenTTL = envDuration("TOKEN_TTL", 15*time.Minute)
+	RefreshTokenTTL = envDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+	SessionMaxAge = envDuration("SESSION_MAX_AGE", 90*24*time.Hour)
+
+	TOTPIssuer = os.Getenv("TOTP_ISSUER")
+	if TOTPIssuer == "" {
+		TOTPIssuer = "GoFamTree"
+	}
+
+	TrustProxyHeaders = os.Getenv("TRUST_PROXY_HEADERS") == "true"
+	TrustedProxyHops = envInt("TRUSTED_PROXY_HOPS", 1)
+
+	LoginMaxFailures = envInt("LOGIN_MAX_FAILURES", 5)
+	LoginMaxIPFailures = envInt("LOGIN_MAX_IP_FAILURES", 20)
+	LoginFailureWindow = envDuration("LOGIN_FAILURE_WINDOW", 15*time.Minute)
+	LoginLockout = envDuration("LOGIN_LOCKOUT", 15*time.Minute)
+	LoginMaxDelay = envDuration("LOGIN_MAX_DELAY", 30*time.Second)
+
+	RegistrationInviteTTL = envDuration("REGISTRATION_INVITE_TTL", 7*24*time.Hour)
+
+	log.Printf("Auth configured - tokens expire after %s, sessions after %s idle", TokenTTL, RefreshTokenTTL)
+}
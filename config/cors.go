@@ -0,0 +1,64 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	// Origins allowed to call the API from a browser. "*" allows any origin and
+	// an entry such as "https://*.example.com" allows every subdomain.
+	CORSAllowedOrigins   []string
+	CORSAllowedMethods   []string
+	CORSAllowedHeaders   []string
+	CORSAllowCredentials bool
+	CORSMaxAge           time.Duration // how long browsers may cache a preflight response
+)
+
+func InitCORS() {
+	CORSAllowedOrigins = envList("CORS_ALLOWED_ORIGINS", []string{"*"})
+	CORSAllowedMethods = envList("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"})
+	CORSAllowedHeaders = envList("CORS_ALLOWED_HEADERS", []string{"Content-Type", "Authorization", "X-Share-Password"})
+	CORSAllowCredentials = os.Getenv("CORS_ALLOW_CREDENTIALS") == "true"
+	CORSMaxAge = envDuration("CORS_MAX_AGE", 10*time.Minute)
+
+	for i, method := range CORSAllowedMethods {
+		CORSAllowedMethods[i] = strings.ToUpper(method)
+	}
+
+	// Browsers refuse credentialed responses with a wildcard origin
+	if CORSAllowCredentials && CORSAllowsAnyOrigin() {
+		log.Fatal("CORS_ALLOW_CREDENTIALS=true requires an explicit CORS_ALLOWED_ORIGINS list")
+	}
+
+	log.Printf("CORS configured - allowed origins: %s", strings.Join(CORSAllowedOrigins, ", "))
+}
+
+// CORSAllowsAnyOrigin reports whether the wildcard origin is configured
+func CORSAllowsAnyOrigin() bool {
+	for _, allowed := range CORSAllowedOrigins {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// CORSOriginAllowed reports whether a request Origin matches the configured list
+func CORSOriginAllowed(origin string) bool {
+	for _, allowed := range CORSAllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		// "https://*.example.com" matches "https://app.example.com" but not "https://example.com"
+		if scheme, domain, ok := strings.Cut(strings.ToLower(allowed), "*."); ok {
+			host, found := strings.CutPrefix(strings.ToLower(origin), scheme)
+			if found && strings.HasSuffix(host, "."+domain) && !strings.Contains(host, "/") {
+				return true
+			}
+		}
+	}
+	return false
+}
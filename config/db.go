@@ -44,15 +44,31 @@ func InitDB() {
 		log.Println("psql -d gofamtree_new -f create_tables_with_sample_data.sql")
 	} else {
 		log.Println("Database schema verified - tables exist")
+		log.Println("When upgrading, apply any new scripts in migrations/ in order")
 	}
 
 	// Uncomment this section if you want to use GORM auto-migration instead of manual SQL:
 	/*
 	err = DB.AutoMigrate(
 		&models.Admin{},
+		&models.RegistrationInvite{},
+		&models.AdminIdentity{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.APIToken{},
+		&models.PasswordReset{},
+		&models.RecoveryCode{},
 		&models.House{},
+		&models.HouseMember{},
+		&models.ShareLink{},
+		&models.Place{},
+		&models.PlaceName{},
 		&models.Person{},
+		&models.PersonName{},
 		&models.Relation{},
+		&models.Event{},
+		&models.EventParticipant{},
+		&models.AuditLog{},
 	)
 	
 	if err != nil {
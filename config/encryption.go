@@ -0,0 +1,44 @@
+package config
+
+import (
+	"encoding/base64"
+	"log"
+	"strings"
+)
+
+// EncryptionKey is one AES-256 key for field-level encryption at rest
+type EncryptionKey struct {
+	ID  string
+	Key []byte
+}
+
+// EncryptionKeys holds every key that may still decrypt stored data. The first
+// one encrypts new values; the rest are kept until rotation has re-encrypted
+// everything written with them. Empty when encryption is disabled.
+var EncryptionKeys []EncryptionKey
+
+func InitEncryption() {
+	// ENCRYPTION_KEYS=<id>:<base64 32-byte key>[,<old id>:<old key>...]
+	for _, entry := range envList("ENCRYPTION_KEYS", nil) {
+		id, encoded, found := strings.Cut(entry, ":")
+		if !found || id == "" || strings.ContainsAny(id, ": ") {
+			log.Fatalf("Invalid ENCRYPTION_KEYS entry %q (use <id>:<base64 key>)", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != 32 {
+			log.Fatalf("Invalid ENCRYPTION_KEYS key %q: must be 32 bytes, base64-encoded", id)
+		}
+		for _, existing := range EncryptionKeys {
+			if existing.ID == id {
+				log.Fatalf("Duplicate ENCRYPTION_KEYS id %q", id)
+			}
+		}
+		EncryptionKeys = append(EncryptionKeys, EncryptionKey{ID: id, Key: key})
+	}
+
+	if len(EncryptionKeys) == 0 {
+		log.Println("WARNING: ENCRYPTION_KEYS not set - person contact details and descriptions are stored unencrypted")
+		return
+	}
+	log.Printf("Field encryption configured - active key %q, %d key(s) loaded", EncryptionKeys[0].ID, len(EncryptionKeys))
+}
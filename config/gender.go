@@ -0,0 +1,38 @@
+package config
+
+import (
+	"log"
+	"regexp"
+	"strings"
+)
+
+// Genders a person may be recorded with. GENDERS replaces the default list,
+// but unknown and other are always part of it so that every relative can be
+// recorded, whatever the sources say.
+var Genders []string
+
+// Genders every vocabulary includes
+const (
+	GenderOther   = "other"
+	GenderUnknown = "unknown" // recorded when the sources do not say
+)
+
+// genderPattern matches the values the persons.gender CHECK constraint accepts
+var genderPattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+func InitGender() {
+	Genders = nil
+	seen := make(map[string]bool)
+	for _, gender := range append(envList("GENDERS", []string{"male", "female"}), GenderOther, GenderUnknown) {
+		gender = strings.ToLower(gender)
+		if !genderPattern.MatchString(gender) {
+			log.Fatal("Invalid GENDERS entry (use lowercase letters, digits, - and _):", gender)
+		}
+		if !seen[gender] {
+			seen[gender] = true
+			Genders = append(Genders, gender)
+		}
+	}
+
+	log.Printf("Genders configured - %s", strings.Join(Genders, ", "))
+}
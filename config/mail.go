@@ -0,0 +1,58 @@
+package config
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+var (
+	MailDriver       string // smtp, file or log
+	MailFrom         string
+	SMTPAddr         string
+	SMTPUsername     string
+	SMTPPassword     string
+	MailOutboxDir    string
+	AppBaseURL       string
+	PasswordResetTTL time.Duration
+)
+
+func InitMail() {
+	MailDriver = os.Getenv("MAIL_DRIVER")
+	if MailDriver == "" {
+		MailDriver = "log"
+	}
+
+	MailFrom = os.Getenv("MAIL_FROM")
+	if MailFrom == "" {
+		MailFrom = "gofamtree@localhost"
+	}
+
+	// Defaults suit a local SMTP stand-in such as MailHog or smtp4dev
+	SMTPAddr = os.Getenv("SMTP_ADDR")
+	if SMTPAddr == "" {
+		SMTPAddr = "localhost:1025"
+	}
+	SMTPUsername = os.Getenv("SMTP_USERNAME")
+	SMTPPassword = os.Getenv("SMTP_PASSWORD")
+
+	MailOutboxDir = os.Getenv("MAIL_OUTBOX_DIR")
+	if MailOutboxDir == "" {
+		MailOutboxDir = "outbox"
+	}
+
+	AppBaseURL = os.Getenv("APP_BASE_URL")
+
+	PasswordResetTTL = envDuration("PASSWORD_RESET_TTL", time.Hour)
+
+	switch MailDriver {
+	case "smtp":
+		log.Printf("Mail configured - sending through SMTP at %s", SMTPAddr)
+	case "file":
+		log.Printf("Mail configured - writing messages to %s/", MailOutboxDir)
+	case "log":
+		log.Println("Mail configured - messages are written to the server log")
+	default:
+		log.Fatal("Invalid MAIL_DRIVER (use smtp, file or log):", MailDriver)
+	}
+}
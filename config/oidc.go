@@ -0,0 +1,64 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strings"
+)
+
+var (
+	// OpenID Connect single sign-on. Disabled unless OIDC_ISSUER is set.
+	OIDCIssuer       string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string // this API's /admin/oidc/callback as registered with the provider
+	OIDCScopes       []string
+
+	// Claim mapping
+	OIDCUsernameClaim string
+	OIDCEmailClaim    string
+
+	// Create an admin on first sign-in when no account can be linked. Off by
+	// default: anyone who can sign in at the provider would get an account.
+	OIDCCreateAdmins bool
+
+	// Front-end page that receives the login result in the URL fragment.
+	// The callback answers with JSON when unset.
+	OIDCPostLoginRedirect string
+)
+
+func InitOIDC() {
+	OIDCIssuer = strings.TrimSuffix(os.Getenv("OIDC_ISSUER"), "/")
+	if OIDCIssuer == "" {
+		log.Println("OIDC disabled - set OIDC_ISSUER to enable single sign-on")
+		return
+	}
+
+	OIDCClientID = os.Getenv("OIDC_CLIENT_ID")
+	OIDCClientSecret = os.Getenv("OIDC_CLIENT_SECRET")
+	OIDCRedirectURL = os.Getenv("OIDC_REDIRECT_URL")
+	if OIDCClientID == "" || OIDCRedirectURL == "" {
+		log.Fatal("OIDC_ISSUER requires OIDC_CLIENT_ID and OIDC_REDIRECT_URL")
+	}
+
+	OIDCScopes = envList("OIDC_SCOPES", []string{"openid", "profile", "email"})
+
+	OIDCUsernameClaim = os.Getenv("OIDC_USERNAME_CLAIM")
+	if OIDCUsernameClaim == "" {
+		OIDCUsernameClaim = "preferred_username"
+	}
+	OIDCEmailClaim = os.Getenv("OIDC_EMAIL_CLAIM")
+	if OIDCEmailClaim == "" {
+		OIDCEmailClaim = "email"
+	}
+
+	OIDCCreateAdmins = os.Getenv("OIDC_CREATE_ADMINS") == "true"
+	OIDCPostLoginRedirect = os.Getenv("OIDC_POST_LOGIN_REDIRECT")
+
+	log.Printf("OIDC configured - issuer %s, client %s", OIDCIssuer, OIDCClientID)
+}
+
+// OIDCEnabled reports whether single sign-on is configured
+func OIDCEnabled() bool {
+	return OIDCIssuer != ""
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"log"
+	"os"
+)
+
+var (
+	PasswordHashAlgorithm string // argon2id or bcrypt, used for new hashes
+	BcryptCost            int
+	Argon2Memory          int // KiB
+	Argon2Iterations      int
+	Argon2Parallelism     int
+
+	// Password policy applied when a password is chosen
+	PasswordMinLength    int
+	PasswordMaxLength    int
+	PasswordBreachedList string // file of known-breached passwords, one per line
+)
+
+func InitPassword() {
+	PasswordHashAlgorithm = os.Getenv("PASSWORD_HASH_ALGORITHM")
+	if PasswordHashAlgorithm == "" {
+		PasswordHashAlgorithm = "argon2id"
+	}
+	if PasswordHashAlgorithm != "argon2id" && PasswordHashAlgorithm != "bcrypt" {
+		log.Fatal("Invalid PASSWORD_HASH_ALGORITHM (use argon2id or bcrypt):", PasswordHashAlgorithm)
+	}
+
+	BcryptCost = envInt("BCRYPT_COST", 10)
+	if BcryptCost < 4 || BcryptCost > 31 {
+		log.Fatalf("Invalid BCRYPT_COST: %d", BcryptCost)
+	}
+
+	Argon2Memory = envInt("ARGON2_MEMORY", 64*1024)
+	Argon2Iterations = envInt("ARGON2_ITERATIONS", 3)
+	Argon2Parallelism = envInt("ARGON2_PARALLELISM", 2)
+	if Argon2Parallelism > 255 {
+		log.Fatalf("Invalid ARGON2_PARALLELISM: %d", Argon2Parallelism)
+	}
+
+	PasswordMinLength = envInt("PASSWORD_MIN_LENGTH", 8)
+	PasswordMaxLength = envInt("PASSWORD_MAX_LENGTH", 128)
+	if PasswordMaxLength < PasswordMinLength {
+		log.Fatal("PASSWORD_MAX_LENGTH must not be below PASSWORD_MIN_LENGTH")
+	}
+	PasswordBreachedList = os.Getenv("PASSWORD_BREACHED_LIST")
+
+	log.Printf("Passwords configured - new hashes use %s", PasswordHashAlgorithm)
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate limit classes. Every route belongs to one.
+const (
+	RateLimitAuth   = "auth"   // login, registration, password reset, token refresh
+	RateLimitRead   = "read"   // GET requests
+	RateLimitWrite  = "write"  // POST, PUT and DELETE requests
+	RateLimitExport = "export" // whole-tree reads such as /family-tree
+	RateLimitIP     = "ip"     // every authenticated route, per client IP before the token is checked
+)
+
+// RateLimit is a token bucket: Requests may be sent in a burst, and the bucket
+// refills at Requests per Period
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// RateLimits holds the limit of each class; a class missing from it is unlimited
+var RateLimits map[string]RateLimit
+
+func InitRateLimit() {
+	RateLimits = map[string]RateLimit{}
+	for _, class := range []struct {
+		name     string
+		fallback RateLimit
+	}{
+		{RateLimitAuth, RateLimit{Requests: 10, Period: time.Minute}},
+		{RateLimitRead, RateLimit{Requests: 300, Period: time.Minute}},
+		{RateLimitWrite, RateLimit{Requests: 60, Period: time.Minute}},
+		{RateLimitExport, RateLimit{Requests: 10, Period: time.Minute}},
+		{RateLimitIP, RateLimit{Requests: 600, Period: time.Minute}},
+	} {
+		key := "RATE_LIMIT_" + strings.ToUpper(class.name)
+		limit, enabled := envRateLimit(key, class.fallback)
+		if enabled {
+			RateLimits[class.name] = limit
+		}
+	}
+
+	log.Printf("Rate limits configured for %d route classes", len(RateLimits))
+}
+
+// envRateLimit parses "<requests>/<period>", e.g. 300/1m. "off" disables the class.
+func envRateLimit(key string, fallback RateLimit) (RateLimit, bool) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, true
+	}
+	if value == "off" {
+		return RateLimit{}, false
+	}
+
+	requests, period, found := strings.Cut(value, "/")
+	limit := RateLimit{}
+	var err error
+	if found {
+		limit.Requests, err = strconv.Atoi(requests)
+		if err == nil {
+			limit.Period, err = time.ParseDuration(period)
+		}
+	}
+	if !found || err != nil || limit.Requests <= 0 || limit.Period <= 0 {
+		log.Fatalf("Invalid %s: %s (use <requests>/<period>, e.g. 300/1m, or off)", key, value)
+	}
+	return limit, true
+}
@@ -2,9 +2,24 @@
 -- Connect to gofamtree_new database before running this script
 
 -- Drop tables if they exist (for clean setup)
+DROP TABLE IF EXISTS audit_log CASCADE;
+DROP TABLE IF EXISTS event_participants CASCADE;
+DROP TABLE IF EXISTS events CASCADE;
 DROP TABLE IF EXISTS relations CASCADE;
+DROP TABLE IF EXISTS person_names CASCADE;
 DROP TABLE IF EXISTS persons CASCADE;
+DROP TABLE IF EXISTS place_names CASCADE;
+DROP TABLE IF EXISTS places CASCADE;
+DROP TABLE IF EXISTS share_links CASCADE;
+DROP TABLE IF EXISTS house_members CASCADE;
 DROP TABLE IF EXISTS houses CASCADE;
+DROP TABLE IF EXISTS api_tokens CASCADE;
+DROP TABLE IF EXISTS password_resets CASCADE;
+DROP TABLE IF EXISTS refresh_tokens CASCADE;
+DROP TABLE IF EXISTS sessions CASCADE;
+DROP TABLE IF EXISTS admin_identities CASCADE;
+DROP TABLE IF EXISTS registration_invites CASCADE;
+DROP TABLE IF EXISTS recovery_codes CASCADE;
 DROP TABLE IF EXISTS admins CASCADE;
 
 -- Create tables
@@ -12,6 +27,86 @@ CREATE TABLE admins (
     id SERIAL PRIMARY KEY,
     username TEXT UNIQUE NOT NULL,
     password TEXT NOT NULL, -- hashed
+    email TEXT UNIQUE, -- optional, used for password resets
+    email_verified_at TIMESTAMP, -- set once mail to the address reached the admin
+    locked_until TIMESTAMP, -- set after repeated failed logins
+    is_superadmin BOOLEAN NOT NULL DEFAULT FALSE, -- may manage admins and registration invites
+    disabled_at TIMESTAMP, -- disabled admins cannot log in
+    totp_secret TEXT, -- base32 TOTP secret, set during 2FA enrollment
+    totp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    totp_last_counter BIGINT NOT NULL DEFAULT 0, -- last accepted TOTP time step
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE admin_identities (
+    id SERIAL PRIMARY KEY,
+    admin_id INTEGER NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    issuer TEXT NOT NULL,
+    subject TEXT NOT NULL, -- the provider's "sub" claim
+    email TEXT,
+    last_login_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(issuer, subject)
+);
+
+CREATE TABLE sessions (
+    id SERIAL PRIMARY KEY,
+    admin_id INTEGER NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    device TEXT, -- short description derived from the user agent
+    user_agent TEXT,
+    ip_address TEXT, -- client IP of the latest login or refresh
+    expires_at TIMESTAMP NOT NULL, -- absolute limit, refreshing cannot extend past it
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    session_id INTEGER NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    token_hash TEXT UNIQUE NOT NULL, -- SHA-256 of the refresh token
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP, -- set when rotated; presenting it again revokes the session
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE registration_invites (
+    id SERIAL PRIMARY KEY,
+    token_hash TEXT UNIQUE NOT NULL, -- SHA-256 of the invite token
+    email TEXT, -- if set, the new account must use this address
+    created_by INTEGER REFERENCES admins(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    used_by INTEGER REFERENCES admins(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE recovery_codes (
+    id SERIAL PRIMARY KEY,
+    admin_id INTEGER NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL, -- SHA-256 of the normalized code
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE password_resets (
+    id SERIAL PRIMARY KEY,
+    admin_id INTEGER NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    token_hash TEXT UNIQUE NOT NULL, -- SHA-256 of the emailed token
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE api_tokens (
+    id SERIAL PRIMARY KEY,
+    admin_id INTEGER NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL, -- hashed
+    scope TEXT NOT NULL DEFAULT 'read' CHECK (scope IN ('read', 'read-write')),
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
     created_at TIMESTAMP DEFAULT NOW()
 );
 
@@ -19,17 +114,89 @@ CREATE TABLE houses (
     id SERIAL PRIMARY KEY,
     name TEXT NOT NULL,
     created_by INTEGER NOT NULL REFERENCES admins(id),
+    created_at TIMESTAMP DEFAULT NOW(),
+    privacy_min_role TEXT NOT NULL DEFAULT 'editor' CHECK (privacy_min_role IN ('owner', 'editor', 'viewer')), -- lowest role that sees living persons in full
+    privacy_living_years INTEGER NOT NULL DEFAULT 100 CHECK (privacy_living_years > 0) -- persons born within this many years are presumed living
+);
+
+CREATE TABLE house_members (
+    id SERIAL PRIMARY KEY,
+    house_id INTEGER NOT NULL REFERENCES houses(id) ON DELETE CASCADE,
+    admin_id INTEGER NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'active')),
+    invited_by INTEGER REFERENCES admins(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    accepted_at TIMESTAMP,
+    UNIQUE(house_id, admin_id)
+);
+
+CREATE TABLE share_links (
+    id SERIAL PRIMARY KEY,
+    house_id INTEGER NOT NULL REFERENCES houses(id) ON DELETE CASCADE,
+    name TEXT, -- label shown to owners
+    token_hash TEXT UNIQUE NOT NULL, -- SHA-256 of the link token
+    password_hash TEXT, -- optional password, hashed like admin passwords
+    expires_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    use_count INTEGER NOT NULL DEFAULT 0,
+    last_used_at TIMESTAMP,
+    created_by INTEGER REFERENCES admins(id) ON DELETE SET NULL,
     created_at TIMESTAMP DEFAULT NOW()
 );
 
+-- Places table (village -> district -> province -> country)
+CREATE TABLE places (
+    id SERIAL PRIMARY KEY,
+    house_id INTEGER NOT NULL REFERENCES houses(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    place_type TEXT NOT NULL CHECK (place_type IN ('village', 'district', 'province', 'country')),
+    parent_id INTEGER REFERENCES places(id) ON DELETE SET NULL, -- enclosing place
+    latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90),
+    longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180),
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Alternate and historical names of places, e.g. Batavia for Jakarta
+CREATE TABLE place_names (
+    id SERIAL PRIMARY KEY,
+    place_id INTEGER NOT NULL REFERENCES places(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('alternate', 'historical')),
+    language TEXT,
+    from_date TEXT, -- genealogical dates as entered
+    to_date TEXT
+);
+
 CREATE TABLE persons (
     id SERIAL PRIMARY KEY,
     house_id INTEGER NOT NULL REFERENCES houses(id),
     name TEXT NOT NULL,
     contact TEXT,
     description TEXT,
-    gender TEXT CHECK (gender IN ('male', 'female')),
-    dob DATE,
+    gender TEXT NOT NULL DEFAULT 'unknown' CHECK (gender ~ '^[a-z][a-z0-9_-]*$'), -- one of GENDERS
+    dob TEXT, -- as entered, e.g. '1925-03-15', 'March 1925' or 'about 1890'
+    dob_sort DATE, -- first day dob can stand for, for ordering
+    dod TEXT, -- date of death, same formats as dob
+    dod_sort DATE,
+    birth_place_id INTEGER REFERENCES places(id) ON DELETE SET NULL,
+    death_place_id INTEGER REFERENCES places(id) ON DELETE SET NULL,
+    is_living BOOLEAN, -- NULL means decide from dates
+    cause_of_death TEXT,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Person names table (birth, married, nickname, alias)
+CREATE TABLE person_names (
+    id SERIAL PRIMARY KEY,
+    person_id INTEGER NOT NULL REFERENCES persons(id) ON DELETE CASCADE,
+    name_type TEXT NOT NULL CHECK (name_type IN ('birth', 'married', 'nickname', 'alias')),
+    prefix TEXT,
+    given_name TEXT,
+    surname TEXT,
+    suffix TEXT,
+    language TEXT, -- language or script, e.g. 'zh-Hant'
+    preferred BOOLEAN NOT NULL DEFAULT FALSE,
     created_at TIMESTAMP DEFAULT NOW()
 );
 
@@ -43,26 +210,103 @@ CREATE TABLE relations (
     UNIQUE(person_id, related_to_id, relation_type)
 );
 
+-- Events table (birth, baptism, marriage, ...)
+CREATE TABLE events (
+    id SERIAL PRIMARY KEY,
+    house_id INTEGER NOT NULL REFERENCES houses(id) ON DELETE CASCADE,
+    event_type TEXT NOT NULL CHECK (event_type IN ('birth', 'baptism', 'marriage', 'divorce', 'migration', 'residence', 'occupation', 'death', 'burial')),
+    date TEXT, -- genealogical date as entered, e.g. 'about 1890'
+    date_sort DATE, -- first day date can stand for, for ordering
+    place TEXT, -- as written in the source
+    place_id INTEGER REFERENCES places(id) ON DELETE SET NULL,
+    description TEXT,
+    relation_id INTEGER REFERENCES relations(id) ON DELETE SET NULL, -- spouse relation of a marriage or divorce
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Persons taking part in an event
+CREATE TABLE event_participants (
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    person_id INTEGER NOT NULL REFERENCES persons(id) ON DELETE CASCADE,
+    role TEXT, -- e.g. principal, spouse, witness, godparent
+    PRIMARY KEY (event_id, person_id)
+);
+
+-- Audit log (one row per create, update or delete; kept after the entity is gone)
+CREATE TABLE audit_log (
+    id SERIAL PRIMARY KEY,
+    house_id INTEGER,
+    actor_id INTEGER NOT NULL,
+    actor_username TEXT,
+    api_token_id INTEGER,
+    action TEXT NOT NULL CHECK (action IN ('create', 'update', 'delete')),
+    entity_type TEXT NOT NULL,
+    entity_id INTEGER NOT NULL,
+    before JSONB,
+    after JSONB,
+    client_ip TEXT,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
 -- Create indexes for better performance
+CREATE INDEX idx_admin_identities_admin_id ON admin_identities(admin_id);
+CREATE INDEX idx_sessions_admin_id ON sessions(admin_id);
+CREATE INDEX idx_refresh_tokens_session_id ON refresh_tokens(session_id);
+CREATE INDEX idx_recovery_codes_admin_id ON recovery_codes(admin_id);
+CREATE INDEX idx_password_resets_admin_id ON password_resets(admin_id);
+CREATE INDEX idx_api_tokens_admin_id ON api_tokens(admin_id);
 CREATE INDEX idx_houses_created_by ON houses(created_by);
+CREATE INDEX idx_house_members_admin_id ON house_members(admin_id);
+CREATE INDEX idx_share_links_house_id ON share_links(house_id);
 CREATE INDEX idx_persons_house_id ON persons(house_id);
+CREATE INDEX idx_places_house_id ON places(house_id);
+CREATE INDEX idx_places_parent_id ON places(parent_id);
+CREATE INDEX idx_place_names_place_id ON place_names(place_id);
+CREATE INDEX idx_persons_birth_place_id ON persons(birth_place_id);
+CREATE INDEX idx_persons_death_place_id ON persons(death_place_id);
+CREATE INDEX idx_person_names_person_id ON person_names(person_id);
+CREATE UNIQUE INDEX idx_person_names_preferred ON person_names(person_id) WHERE preferred;
 CREATE INDEX idx_relations_house_id ON relations(house_id);
 CREATE INDEX idx_relations_person_id ON relations(person_id);
 CREATE INDEX idx_relations_related_to_id ON relations(related_to_id);
+CREATE INDEX idx_events_house_id ON events(house_id);
+CREATE INDEX idx_events_relation_id ON events(relation_id);
+CREATE INDEX idx_events_place_id ON events(place_id);
+CREATE INDEX idx_event_participants_person_id ON event_participants(person_id);
+CREATE INDEX idx_audit_log_house_id ON audit_log(house_id);
+CREATE INDEX idx_audit_log_entity ON audit_log(entity_type, entity_id);
+CREATE INDEX idx_audit_log_actor_id ON audit_log(actor_id);
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at);
 
 -- Insert sample admin (password is 'password123' hashed with bcrypt)
-INSERT INTO admins (username, password, created_at) VALUES 
-('admin', '$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi', NOW());
+INSERT INTO admins (username, password, is_superadmin, created_at) VALUES 
+('admin', '$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi', TRUE, NOW());
 
 -- Insert sample house
 INSERT INTO houses (name, created_by, created_at) VALUES 
 ('Johnson Family Dynasty', 1, NOW());
 
+-- The sample admin owns the sample house
+INSERT INTO house_members (house_id, admin_id, role, status, created_at, accepted_at) VALUES 
+(1, 1, 'owner', 'active', NOW(), NOW());
+
+-- Sample places, from the country down
+INSERT INTO places (house_id, name, place_type, parent_id, latitude, longitude, created_at) VALUES 
+(1, 'United States', 'country', NULL, NULL, NULL, NOW()),
+(1, 'Illinois', 'province', 1, NULL, NULL, NOW()),
+(1, 'Sangamon County', 'district', 2, NULL, NULL, NOW()),
+(1, 'Springfield', 'village', 3, 39.7817, -89.6501, NOW()),
+(1, 'Riverton', 'village', 3, 39.8442, -89.5390, NOW());
+
+INSERT INTO place_names (place_id, name, kind, language, from_date, to_date) VALUES 
+(4, 'Calhoun', 'historical', NULL, '1821', '1832'),
+(5, 'Jimtown', 'historical', NULL, NULL, 'before 1870');
+
 -- Insert 4 Generations of Sample Data
 -- Generation 1: Great-Grandparents (Born 1920s)
-INSERT INTO persons (house_id, name, contact, description, gender, dob, created_at) VALUES 
-(1, 'William Johnson Sr.', 'william.sr@example.com', 'Great-grandfather, family patriarch', 'male', '1925-03-15', NOW()),
-(1, 'Mary Johnson', 'mary.johnson@example.com', 'Great-grandmother, beloved matriarch', 'female', '1928-07-22', NOW());
+INSERT INTO persons (house_id, name, contact, description, gender, dob, dod, birth_place_id, death_place_id, cause_of_death, created_at) VALUES 
+(1, 'William Johnson Sr.', 'william.sr@example.com', 'Great-grandfather, family patriarch', 'male', '1925-03-15', '2003-02-11', 4, 4, 'Heart failure', NOW()),
+(1, 'Mary Johnson', 'mary.johnson@example.com', 'Great-grandmother, beloved matriarch', 'female', '1928-07-22', '2010-05-30', 5, 4, NULL, NOW());
 
 -- Generation 2: Grandparents (Born 1940s-1950s)
 INSERT INTO persons (house_id, name, contact, description, gender, dob, created_at) VALUES 
@@ -87,6 +331,16 @@ INSERT INTO persons (house_id, name, contact, description, gender, dob, created_
 (1, 'Daniel Johnson', 'daniel.johnson@example.com', 'David and Jennifers eldest son', 'male', '2006-07-18', NOW()),
 (1, 'Sophia Johnson', 'sophia.johnson@example.com', 'David and Jennifers youngest daughter', 'female', '2010-03-12', NOW());
 
+-- Other names: Mary's maiden name and a nickname
+INSERT INTO person_names (person_id, name_type, given_name, surname, suffix, preferred, created_at) VALUES 
+(1, 'birth', 'William', 'Johnson', 'Sr.', TRUE, NOW()),
+(1, 'nickname', 'Bill', NULL, NULL, FALSE, NOW()),
+(2, 'birth', 'Mary', 'Whitfield', NULL, FALSE, NOW()),
+(2, 'married', 'Mary', 'Johnson', NULL, TRUE, NOW());
+
+-- The sample dates are all exact, so they sort as themselves
+UPDATE persons SET dob_sort = dob::date, dod_sort = dod::date;
+
 -- Create Relationships
 -- Generation 1: Great-Grandparents (Spouse relationship)
 INSERT INTO relations (house_id, person_id, related_to_id, relation_type, created_at) VALUES 
@@ -178,6 +432,24 @@ INSERT INTO relations (house_id, person_id, related_to_id, relation_type, create
 (1, 13, 16, 'sibling', NOW()), -- Matthew ↔ Sophia (cousins)
 (1, 16, 13, 'sibling', NOW());
 
+-- Life events of the great-grandparents
+INSERT INTO events (house_id, event_type, date, date_sort, place, place_id, description, relation_id, created_at) VALUES 
+(1, 'birth', '1925-03-15', '1925-03-15', 'Springfield', 4, NULL, NULL, NOW()),
+(1, 'birth', '1928-07-22', '1928-07-22', 'Riverton', 5, NULL, NULL, NOW()),
+(1, 'marriage', 'June 1947', '1947-06-01', 'Springfield', 4, 'Married at St. Mary''s Church', 1, NOW()),
+(1, 'occupation', 'between 1950 and 1985', '1950-01-01', 'Springfield', 4, 'Machinist at the Springfield works', NULL, NOW()),
+(1, 'death', '2003-02-11', '2003-02-11', 'Springfield', 4, NULL, NULL, NOW()),
+(1, 'burial', 'about 2003', '2003-01-01', 'Springfield Cemetery', 4, NULL, NULL, NOW());
+
+INSERT INTO event_participants (event_id, person_id, role) VALUES 
+(1, 1, 'principal'),
+(2, 2, 'principal'),
+(3, 1, 'spouse'),    -- William Sr. ↔ Mary
+(3, 2, 'spouse'),
+(4, 1, 'principal'),
+(5, 1, 'principal'),
+(6, 1, 'principal');
+
 -- Display summary
 DO $$
 BEGIN
@@ -185,8 +457,12 @@ BEGIN
     RAISE NOTICE 'Created:';
     RAISE NOTICE '- % admins', (SELECT COUNT(*) FROM admins);
     RAISE NOTICE '- % houses', (SELECT COUNT(*) FROM houses);
+    RAISE NOTICE '- % house members', (SELECT COUNT(*) FROM house_members);
     RAISE NOTICE '- % persons (4 generations)', (SELECT COUNT(*) FROM persons);
+    RAISE NOTICE '- % person names', (SELECT COUNT(*) FROM person_names);
     RAISE NOTICE '- % relations', (SELECT COUNT(*) FROM relations);
+    RAISE NOTICE '- % events', (SELECT COUNT(*) FROM events);
+    RAISE NOTICE '- % places', (SELECT COUNT(*) FROM places);
     RAISE NOTICE '';
     RAISE NOTICE 'Family Structure:';
     RAISE NOTICE 'Generation 1: William Sr. & Mary (Great-grandparents)';
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"gofamtree/config"
+	"gofamtree/models"
+	"gofamtree/utils"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+// currentAdminID returns the admin authenticated by the auth middleware
+func currentAdminID(r *http.Request) uint {
+	return utils.AdminIDFromContext(r.Context())
+}
+
+// accessibleHouseIDs is a subquery of the house IDs where the caller is an
+// active member holding at least minRole
+func accessibleHouseIDs(r *http.Request, minRole string) *gorm.DB {
+	return config.DB.Model(&models.HouseMember{}).Select("house_id").
+		Where("admin_id = ? AND status = ? AND role IN ?",
+			currentAdminID(r), models.MemberStatusActive, models.RolesAtLeast(minRole))
+}
+
+// houseRole returns the caller's active role on a house, or "" if they are not a member
+func houseRole(r *http.Request, houseID uint) string {
+	var member models.HouseMember
+	if err := config.DB.Where("house_id = ? AND admin_id = ? AND status = ?",
+		houseID, currentAdminID(r), models.MemberStatusActive).First(&member).Error; err != nil {
+		return ""
+	}
+	return member.Role
+}
+
+// hasRole reports whether role includes the permissions of minRole
+func hasRole(role, minRole string) bool {
+	for _, allowed := range models.RolesAtLeast(minRole) {
+		if role == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// requireHouseRole checks the caller's role on a house. It answers 404 when the
+// caller is not a member, so house IDs do not leak, and 403 when the role is too low.
+func requireHouseRole(w http.ResponseWriter, r *http.Request, houseID uint, minRole string) bool {
+	role := houseRole(r, houseID)
+	if role == "" {
+		WriteError(w, r, http.StatusNotFound, ErrCodeHouseNotFound, "House not found")
+		return false
+	}
+	if !hasRole(role, minRole) {
+		WriteError(w, r, http.StatusForbidden, ErrCodeInsufficientRole, "Requires "+minRole+" role on this house")
+		return false
+	}
+	return true
+}
@@ -2,11 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"gofamtree/config"
 	"gofamtree/models"
 	"gofamtree/utils"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type LoginInput struct {
@@ -15,70 +21,273 @@ type LoginInput struct {
 }
 
 type RegisterInput struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	Email       string `json:"email"`        // optional, used for password resets
+	InviteToken string `json:"invite_token"` // required once the first admin exists
 }
 
+func (input LoginInput) validate(v *validator) {
+	v.required("username", input.Username)
+	v.maxLength("username", input.Username, maxUsernameLength)
+	v.required("password", input.Password)
+	v.maxLength("password", input.Password, maxSecretLength)
+}
+
+func (input RegisterInput) validate(v *validator) {
+	v.required("username", input.Username)
+	v.maxLength("username", input.Username, maxUsernameLength)
+	v.required("password", input.Password)
+	v.email("email", input.Email)
+	v.maxLength("invite_token", input.InviteToken, maxSecretLength)
+}
+
+var errRegistrationClosed = errors.New("registration is closed")
+
 type LoginResponse struct {
-	Message string       `json:"message"`
-	Admin   models.Admin `json:"admin"`
+	Message          string       `json:"message"`
+	Token            string       `json:"token"`
+	TokenType        string       `json:"token_type"`
+	ExpiresAt        time.Time    `json:"expires_at"`
+	RefreshToken     string       `json:"refresh_token"` // single-use, exchanged at /admin/token/refresh
+	RefreshExpiresAt time.Time    `json:"refresh_expires_at"`
+	Admin            models.Admin `json:"admin"`
+}
+
+// MFAChallengeResponse is returned by AdminLogin when the admin has 2FA enabled.
+// The MFA token is exchanged for an access token at /admin/login/2fa.
+type MFAChallengeResponse struct {
+	Message     string    `json:"message"`
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    string    `json:"mfa_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
 }
 
 func AdminLogin(w http.ResponseWriter, r *http.Request) {
 	var input LoginInput
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
+	if !decodeInput(w, r, &input) {
+		return
+	}
+
+	now := time.Now()
+	userKey := usernameKey(input.Username)
+	clientKey := ipKey(utils.ClientIP(r))
+
+	// Progressive delay and temporary lockout after repeated failures
+	wait := loginLimiter.wait(userKey, now)
+	if ipWait := loginLimiter.wait(clientKey, now); ipWait > wait {
+		wait = ipWait
+	}
+	if wait > 0 {
+		tooManyAttempts(w, r, wait)
 		return
 	}
 
 	var admin models.Admin
-	if err := config.DB.Where("username = ?", input.Username).First(&admin).Error; err != nil {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+	found := config.DB.Where("username = ?", input.Username).First(&admin).Error == nil
+
+	// Always compare against a hash so unknown usernames take as long as wrong passwords
+	hash := passwordDummyHash()
+	if found {
+		hash = admin.Password
+	}
+	passwordOK := utils.CheckPasswordHash(input.Password, hash) && found
+
+	// A lockout persisted on the account outlives server restarts
+	if found && admin.LockedUntil != nil && now.Before(*admin.LockedUntil) {
+		tooManyAttempts(w, r, admin.LockedUntil.Sub(now))
+		return
+	}
+
+	if !passwordOK {
+		locked := loginLimiter.fail(userKey, config.LoginMaxFailures, now)
+		loginLimiter.fail(clientKey, config.LoginMaxIPFailures, now)
+		if locked && found {
+			lockedUntil := now.Add(config.LoginLockout)
+			config.DB.Model(&admin).Update("locked_until", lockedUntil)
+			log.Printf("Admin %d locked until %s after repeated failed logins", admin.ID, lockedUntil.Format(time.RFC3339))
+		}
+		WriteError(w, r, http.StatusUnauthorized, ErrCodeInvalidCredentials, "Invalid credentials")
 		return
 	}
 
-	if !utils.CheckPasswordHash(input.Password, admin.Password) {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+	loginLimiter.reset(userKey)
+	if admin.LockedUntil != nil {
+		config.DB.Model(&admin).Update("locked_until", nil)
+	}
+
+	// Upgrade hashes made with an older algorithm or other parameters while the password is at hand
+	if rehashed, ok := utils.RehashPassword(input.Password, admin.Password); ok {
+		if err := config.DB.Model(&admin).Update("password", rehashed).Error; err != nil {
+			log.Printf("Failed to rehash password for admin %d: %v", admin.ID, err)
+		}
+	}
+
+	if admin.DisabledAt != nil {
+		WriteError(w, r, http.StatusForbidden, ErrCodeAccountDisabled, "Account is disabled")
+		return
+	}
+
+	// With 2FA enabled the password only earns a short-lived challenge token
+	if admin.TOTPEnabled {
+		respondWithMFAChallenge(w, r, admin)
+		return
+	}
+
+	respondWithLoginToken(w, r, admin)
+}
+
+func respondWithMFAChallenge(w http.ResponseWriter, r *http.Request, admin models.Admin) {
+	mfaToken, expiresAt, err := utils.GenerateMFAToken(admin.ID)
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to issue token")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(MFAChallengeResponse{
+		Message:     "Two-factor code required",
+		MFARequired: true,
+		MFAToken:    mfaToken,
+		ExpiresAt:   expiresAt,
+	})
+}
+
+// respondWithLoginToken starts a session once every login step has passed
+func respondWithLoginToken(w http.ResponseWriter, r *http.Request, admin models.Admin) {
+	tokens, err := startSession(r, admin)
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to issue token")
 		return
 	}
 
+	writeLoginResponse(w, "Login successful", admin, tokens)
+}
+
+func writeLoginResponse(w http.ResponseWriter, message string, admin models.Admin, tokens loginTokens) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(LoginResponse{
-		Message: "Login successful",
-		Admin:   admin,
+		Message:          message,
+		Token:            tokens.AccessToken,
+		TokenType:        "Bearer",
+		ExpiresAt:        tokens.ExpiresAt,
+		RefreshToken:     tokens.RefreshToken,
+		RefreshExpiresAt: tokens.RefreshExpiresAt,
+		Admin:            admin,
 	})
 }
 
+// AdminRegister creates an admin account. The very first account bootstraps the
+// installation and becomes a superadmin; every later one needs an invite token.
 func AdminRegister(w http.ResponseWriter, r *http.Request) {
 	var input RegisterInput
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
+	if !decodeInput(w, r, &input) {
+		return
+	}
+
+	var invite *models.RegistrationInvite
+	if input.InviteToken != "" {
+		invite = &models.RegistrationInvite{}
+		if err := config.DB.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?",
+			utils.HashToken(input.InviteToken), time.Now()).First(invite).Error; err != nil {
+			WriteError(w, r, http.StatusBadRequest, ErrCodeInviteInvalid, "Invalid or expired invite")
+			return
+		}
+		// An invite sent to an address can only be used for that address
+		if invite.Email != nil {
+			if input.Email == "" {
+				input.Email = *invite.Email
+			} else if !strings.EqualFold(input.Email, *invite.Email) {
+				WriteError(w, r, http.StatusBadRequest, ErrCodeInviteEmailMismatch, "This invite was issued for a different email address")
+				return
+			}
+		}
+	}
+
+	if err := utils.CheckPasswordPolicy(input.Password, input.Username); err != nil {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeWeakPassword, err.Error())
 		return
 	}
 
 	// Check if username already exists
 	var existingAdmin models.Admin
 	if err := config.DB.Where("username = ?", input.Username).First(&existingAdmin).Error; err == nil {
-		http.Error(w, "Username already exists", http.StatusConflict)
+		WriteError(w, r, http.StatusConflict, ErrCodeUsernameTaken, "Username already exists")
 		return
 	}
 
+	var email *string
+	if input.Email != "" {
+		var emailOwner models.Admin
+		if err := config.DB.Where("email = ?", input.Email).First(&emailOwner).Error; err == nil {
+			WriteError(w, r, http.StatusConflict, ErrCodeEmailTaken, "Email already in use")
+			return
+		}
+		email = &input.Email
+	}
+
 	// Hash password
 	hashedPassword, err := utils.HashPassword(input.Password)
 	if err != nil {
-		http.Error(w, "Failed to hash password", http.StatusInternalServerError)
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to hash password")
 		return
 	}
 
 	admin := models.Admin{
 		Username:  input.Username,
 		Password:  hashedPassword,
+		Email:     email,
 		CreatedAt: time.Now(),
 	}
+	// An invite mailed to the address proves it reaches the new admin
+	if invite != nil && invite.Email != nil {
+		admin.EmailVerifiedAt = &admin.CreatedAt
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Serialize registrations so two callers cannot both become the first admin
+		if err := tx.Exec("LOCK TABLE admins IN SHARE ROW EXCLUSIVE MODE").Error; err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&models.Admin{}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			admin.IsSuperadmin = true
+		} else if invite == nil {
+			return errRegistrationClosed
+		}
+
+		if err := tx.Create(&admin).Error; err != nil {
+			return err
+		}
+		if invite == nil {
+			return nil
+		}
 
-	if err := config.DB.Create(&admin).Error; err != nil {
-		http.Error(w, "Failed to create admin", http.StatusInternalServerError)
+		result := tx.Model(invite).Where("used_at IS NULL").
+			Updates(map[string]interface{}{"used_at": time.Now(), "used_by": admin.ID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err == errRegistrationClosed {
+		WriteError(w, r, http.StatusForbidden, ErrCodeRegistrationClosed, "Registration requires an invite")
+		return
+	}
+	if err == gorm.ErrRecordNotFound {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInviteInvalid, "Invalid or expired invite")
+		return
+	}
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to create admin")
 		return
 	}
 
@@ -89,3 +298,47 @@ func AdminRegister(w http.ResponseWriter, r *http.Request) {
 		"admin":   admin,
 	})
 }
+
+// UnlockAdmin clears a login lockout. The caller must be a superadmin or own a house
+// the locked admin is a member of - nobody can unlock their own account.
+func UnlockAdmin(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from URL path /admin/admins/{id}/unlock
+	path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/admin/admins/"), "/unlock")
+	id, err := strconv.ParseUint(path, 10, 32)
+	if err != nil {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid admin ID")
+		return
+	}
+
+	if uint(id) == currentAdminID(r) {
+		WriteError(w, r, http.StatusForbidden, ErrCodeSelfNotAllowed, "Another owner must unlock your account")
+		return
+	}
+
+	var admin models.Admin
+	if err := config.DB.First(&admin, uint(id)).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodeAdminNotFound, "Admin not found")
+		return
+	}
+
+	var shared int64
+	config.DB.Model(&models.HouseMember{}).
+		Where("admin_id = ? AND status = ? AND house_id IN (?)",
+			admin.ID, models.MemberStatusActive, accessibleHouseIDs(r, models.RoleOwner)).
+		Count(&shared)
+	if shared == 0 && !isSuperadmin(r) {
+		WriteError(w, r, http.StatusForbidden, ErrCodeInsufficientRole, "Requires owner role on a house this admin belongs to")
+		return
+	}
+
+	if err := config.DB.Model(&admin).Update("locked_until", nil).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to unlock admin")
+		return
+	}
+	loginLimiter.reset(usernameKey(admin.Username))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Admin unlocked successfully",
+	})
+}
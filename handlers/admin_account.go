@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gofamtree/config"
+	"gofamtree/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type UpdateAdminInput struct {
+	Username     string `json:"username"`
+	IsSuperadmin *bool  `json:"is_superadmin"` // superadmins only, not on themselves
+}
+
+func (input UpdateAdminInput) validate(v *validator) {
+	v.maxLength("username", input.Username, maxUsernameLength)
+}
+
+// isSuperadmin reports whether the caller may manage admin accounts
+func isSuperadmin(r *http.Request) bool {
+	var admin models.Admin
+	if err := config.DB.Select("id", "is_superadmin").First(&admin, currentAdminID(r)).Error; err != nil {
+		return false
+	}
+	return admin.IsSuperadmin
+}
+
+// requireSuperadmin admits superadmins who logged in. Account management
+// refuses API tokens, so a leaked script token cannot hand out invites or
+// superadmin rights.
+func requireSuperadmin(w http.ResponseWriter, r *http.Request) bool {
+	if !requireInteractiveLogin(w, r) {
+		return false
+	}
+	if !isSuperadmin(r) {
+		WriteError(w, r, http.StatusForbidden, ErrCodeSuperadminRequired, "Requires superadmin")
+		return false
+	}
+	return true
+}
+
+// parseAdminPath extracts the admin ID from /admin/admins/{id}[/action]
+func parseAdminPath(r *http.Request) (uint, error) {
+	path := strings.TrimPrefix(r.URL.Path, "/admin/admins/")
+	if i := strings.Index(path, "/"); i >= 0 {
+		path = path[:i]
+	}
+	id, err := strconv.ParseUint(path, 10, 32)
+	return uint(id), err
+}
+
+func GetAdmins(w http.ResponseWriter, r *http.Request) {
+	if !requireSuperadmin(w, r) {
+		return
+	}
+
+	var admins []models.Admin
+	if err := config.DB.Order("id").Find(&admins).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to fetch admins")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(admins)
+}
+
+// UpdateAdmin renames an admin. Admins may rename themselves; superadmins may
+// rename anyone and grant or withdraw superadmin rights.
+func UpdateAdmin(w http.ResponseWriter, r *http.Request) {
+	id, err := parseAdminPath(r)
+	if err != nil {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid admin ID")
+		return
+	}
+
+	var input UpdateAdminInput
+	if !decodeInput(w, r, &input) {
+		return
+	}
+
+	self := id == currentAdminID(r)
+	if !self && !requireSuperadmin(w, r) {
+		return
+	}
+	if input.IsSuperadmin != nil {
+		if self {
+			WriteError(w, r, http.StatusForbidden, ErrCodeSelfNotAllowed, "You cannot change your own superadmin rights")
+			return
+		}
+		if !requireSuperadmin(w, r) {
+			return
+		}
+	}
+
+	var admin models.Admin
+	if err := config.DB.First(&admin, id).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodeAdminNotFound, "Admin not found")
+		return
+	}
+
+	before := admin
+	if input.Username != "" && input.Username != admin.Username {
+		var existing models.Admin
+		if err := config.DB.Where("username = ?", input.Username).First(&existing).Error; err == nil {
+			WriteError(w, r, http.StatusConflict, ErrCodeUsernameTaken, "Username already exists")
+			return
+		}
+		admin.Username = input.Username
+	}
+	if input.IsSuperadmin != nil {
+		admin.IsSuperadmin = *input.IsSuperadmin
+	}
+
+	if err := config.DB.Save(&admin).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to update admin")
+		return
+	}
+	recordAudit(r, 0, models.AuditUpdate, "admin", admin.ID, before, admin)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(admin)
+}
+
+// DisableAdmin blocks an admin from logging in. Their existing tokens stop working too.
+func DisableAdmin(w http.ResponseWriter, r *http.Request) {
+	setAdminDisabled(w, r, true)
+}
+
+func EnableAdmin(w http.ResponseWriter, r *http.Request) {
+	setAdminDisabled(w, r, false)
+}
+
+func setAdminDisabled(w http.ResponseWriter, r *http.Request, disable bool) {
+	id, err := parseAdminPath(r)
+	if err != nil {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid admin ID")
+		return
+	}
+
+	if !requireSuperadmin(w, r) {
+		return
+	}
+	if id == currentAdminID(r) {
+		WriteError(w, r, http.StatusForbidden, ErrCodeSelfNotAllowed, "You cannot disable or enable your own account")
+		return
+	}
+
+	var admin models.Admin
+	if err := config.DB.First(&admin, id).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodeAdminNotFound, "Admin not found")
+		return
+	}
+
+	before := admin
+	message := "Admin enabled successfully"
+	admin.DisabledAt = nil
+	if disable {
+		now := time.Now()
+		admin.DisabledAt = &now
+		message = "Admin disabled successfully"
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&admin).Update("disabled_at", admin.DisabledAt).Error; err != nil {
+			return err
+		}
+		// A disabled admin stays signed out after being enabled again
+		if disable {
+			return revokeSessions(tx, admin.ID, 0)
+		}
+		return nil
+	})
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to update admin")
+		return
+	}
+	recordAudit(r, 0, models.AuditUpdate, "admin", admin.ID, before, admin)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": message,
+		"admin":   admin,
+	})
+}
+
+// DeleteAdmin removes an admin account. Houses they created or own are handed to
+// the admin named in ?reassign_to (the caller by default), who becomes their owner.
+func DeleteAdmin(w http.ResponseWriter, r *http.Request) {
+	id, err := parseAdminPath(r)
+	if err != nil {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid admin ID")
+		return
+	}
+
+	if !requireSuperadmin(w, r) {
+		return
+	}
+	if id == currentAdminID(r) {
+		WriteError(w, r, http.StatusForbidden, ErrCodeSelfNotAllowed, "You cannot delete your own account")
+		return
+	}
+
+	var admin models.Admin
+	if err := config.DB.First(&admin, id).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodeAdminNotFound, "Admin not found")
+		return
+	}
+
+	heirID := currentAdminID(r)
+	if value := r.URL.Query().Get("reassign_to"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidReassign, "Invalid reassign_to")
+			return
+		}
+		heirID = uint(parsed)
+	}
+	var heir models.Admin
+	if heirID == admin.ID || config.DB.First(&heir, heirID).Error != nil {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidReassign, "reassign_to must name another existing admin")
+		return
+	}
+	if heir.DisabledAt != nil {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidReassign, "Cannot reassign houses to a disabled admin")
+		return
+	}
+
+	var houseIDs []uint
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Every house the admin created or owns goes to the heir
+		if err := tx.Model(&models.House{}).
+			Where("created_by = ? OR id IN (?)", admin.ID,
+				tx.Model(&models.HouseMember{}).Select("house_id").
+					Where("admin_id = ? AND role = ? AND status = ?", admin.ID, models.RoleOwner, models.MemberStatusActive)).
+			Pluck("id", &houseIDs).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, houseID := range houseIDs {
+			var member models.HouseMember
+			err := tx.Where("house_id = ? AND admin_id = ?", houseID, heir.ID).First(&member).Error
+			if err == gorm.ErrRecordNotFound {
+				member = models.HouseMember{HouseID: houseID, AdminID: heir.ID, CreatedAt: now}
+			} else if err != nil {
+				return err
+			}
+			member.Role = models.RoleOwner
+			member.Status = models.MemberStatusActive
+			if member.AcceptedAt == nil {
+				member.AcceptedAt = &now
+			}
+			if err := tx.Save(&member).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&models.House{}).Where("created_by = ?", admin.ID).Update("created_by", heir.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.HouseMember{}).Where("invited_by = ?", admin.ID).Update("invited_by", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.RegistrationInvite{}).Where("created_by = ?", admin.ID).Update("created_by", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("session_id IN (?)", tx.Model(&models.Session{}).Select("id").Where("admin_id = ?", admin.ID)).
+			Delete(&models.RefreshToken{}).Error; err != nil {
+			return err
+		}
+		for _, model := range []interface{}{
+			&models.HouseMember{}, &models.APIToken{}, &models.PasswordReset{}, &models.RecoveryCode{}, &models.AdminIdentity{},
+			&models.Session{},
+		} {
+			if err := tx.Where("admin_id = ?", admin.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&admin).Error
+	})
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to delete admin")
+		return
+	}
+	recordAudit(r, 0, models.AuditDelete, "admin", admin.ID, admin, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":           "Admin deleted successfully",
+		"reassigned_to":     heir.ID,
+		"reassigned_houses": len(houseIDs),
+	})
+}
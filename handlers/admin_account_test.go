@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"gofamtree/models"
+	"gofamtree/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestSuperadminRoutesRefuseAPITokens checks that account management answers
+// 403 to API tokens before it looks at who owns them
+func TestSuperadminRoutesRefuseAPITokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		path    string
+		body    string
+	}{
+		{"list admins", GetAdmins, "GET", "/admin/admins", ""},
+		{"grant superadmin", UpdateAdmin, "PUT", "/admin/admins/2", `{"username":"bob","is_superadmin":true}`},
+		{"disable admin", DisableAdmin, "POST", "/admin/admins/2/disable", ""},
+		{"enable admin", EnableAdmin, "POST", "/admin/admins/2/enable", ""},
+		{"delete admin", DeleteAdmin, "DELETE", "/admin/admins/2", ""},
+		{"create invite", CreateRegistrationInvite, "POST", "/admin/invites", `{}`},
+		{"list invites", GetRegistrationInvites, "GET", "/admin/invites", ""},
+		{"revoke invite", RevokeRegistrationInvite, "DELETE", "/admin/invites/1", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			ctx := utils.WithAdminID(req.Context(), 1)
+			req = req.WithContext(utils.WithAPIToken(ctx, 9, models.TokenScopeReadWrite))
+
+			rec := httptest.NewRecorder()
+			tt.handler(rec, req)
+			if rec.Code != http.StatusForbidden || decodeError(t, rec).Code != ErrCodeInteractiveOnly {
+				t.Errorf("answered %d %s, want 403 %s", rec.Code, rec.Body, ErrCodeInteractiveOnly)
+			}
+		})
+	}
+}
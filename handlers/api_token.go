@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gofamtree/config"
+	"gofamtree/models"
+	"gofamtree/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CreateAPITokenInput struct {
+	Name      string `json:"name"`
+	Scope     string `json:"scope"`      // read/read-write, defaults to read
+	ExpiresAt string `json:"expires_at"` // YYYY-MM-DD or RFC 3339 (optional)
+}
+
+func (input CreateAPITokenInput) validate(v *validator) {
+	v.required("name", input.Name)
+	v.maxLength("name", input.Name, maxLabelLength)
+	v.oneOf("scope", input.Scope, models.TokenScopeRead, models.TokenScopeReadWrite)
+	v.futureTime("expires_at", input.ExpiresAt)
+}
+
+type CreateAPITokenResponse struct {
+	Message  string          `json:"message"`
+	Token    string          `json:"token"` // Only ever returned once
+	APIToken models.APIToken `json:"api_token"`
+}
+
+// requireInteractiveLogin rejects requests made with a personal API token.
+// Everything that manages the account itself needs a login, so a leaked token
+// cannot be used to take it over.
+func requireInteractiveLogin(w http.ResponseWriter, r *http.Request) bool {
+	if tokenID, _ := utils.APITokenFromContext(r.Context()); tokenID != 0 {
+		WriteError(w, r, http.StatusForbidden, ErrCodeInteractiveOnly, "Requires a login; API tokens cannot be used here")
+		return false
+	}
+	return true
+}
+
+func CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	if !requireInteractiveLogin(w, r) {
+		return
+	}
+
+	var input CreateAPITokenInput
+	if !decodeInput(w, r, &input) {
+		return
+	}
+
+	if input.Scope == "" {
+		input.Scope = models.TokenScopeRead
+	}
+
+	var expiresAt *time.Time
+	if input.ExpiresAt != "" {
+		parsed, _ := parseTimestamp(input.ExpiresAt)
+		expiresAt = &parsed
+	}
+
+	secret, err := utils.NewAPITokenSecret()
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to generate token")
+		return
+	}
+
+	// Only a hash of the secret is stored. The secret is random, so a fast
+	// digest is enough and keeps every authenticated request cheap.
+	apiToken := models.APIToken{
+		AdminID:   currentAdminID(r),
+		Name:      strings.TrimSpace(input.Name),
+		TokenHash: utils.HashToken(secret),
+		Scope:     input.Scope,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+
+	if err := config.DB.Create(&apiToken).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to create token")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreateAPITokenResponse{
+		Message:  "Token created - store it now, it will not be shown again",
+		Token:    utils.FormatAPIToken(apiToken.ID, secret),
+		APIToken: apiToken,
+	})
+}
+
+func GetAPITokens(w http.ResponseWriter, r *http.Request) {
+	var tokens []models.APIToken
+	if err := config.DB.Where("admin_id = ?", currentAdminID(r)).Order("id").Find(&tokens).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to fetch tokens")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+func RevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	if !requireInteractiveLogin(w, r) {
+		return
+	}
+
+	// Extract ID from URL path
+	path := strings.TrimPrefix(r.URL.Path, "/admin/tokens/")
+	id, err := strconv.ParseUint(path, 10, 32)
+	if err != nil {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid token ID")
+		return
+	}
+
+	var apiToken models.APIToken
+	if err := config.DB.Where("admin_id = ?", currentAdminID(r)).First(&apiToken, uint(id)).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodeAPITokenNotFound, "Token not found")
+		return
+	}
+
+	if apiToken.RevokedAt == nil {
+		now := time.Now()
+		apiToken.RevokedAt = &now
+		if err := config.DB.Save(&apiToken).Error; err != nil {
+			WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to revoke token")
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Token revoked successfully",
+	})
+}
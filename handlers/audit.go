@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gofamtree/config"
+	"gofamtree/models"
+	"gofamtree/utils"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// auditRelationshipKeys are preloaded associations left out of audit snapshots,
+// so a row only holds the fields of the entity itself
+var auditRelationshipKeys = []string{"admin", "house", "persons", "members", "person", "related_to", "birth_place", "death_place", "location"}
+
+// recordAudit writes one audit_log row for a mutation. The change is already
+// committed at this point, so a failure is logged rather than sent to the client.
+func recordAudit(r *http.Request, houseID uint, action, entityType string, entityID uint, before, after interface{}) {
+	entry := models.AuditLog{
+		ActorID:    currentAdminID(r),
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Before:     auditSnapshot(before),
+		After:      auditSnapshot(after),
+		ClientIP:   utils.ClientIP(r),
+		CreatedAt:  time.Now(),
+	}
+	if houseID != 0 {
+		entry.HouseID = &houseID
+	}
+	if tokenID, _ := utils.APITokenFromContext(r.Context()); tokenID != 0 {
+		entry.APITokenID = &tokenID
+	}
+
+	var actor models.Admin
+	if err := config.DB.Select("username").First(&actor, entry.ActorID).Error; err == nil {
+		entry.ActorUsername = actor.Username
+	}
+
+	if err := config.DB.Create(&entry).Error; err != nil {
+		log.Printf("Failed to write audit log for %s %s %d: %v", action, entityType, entityID, err)
+	}
+}
+
+func auditSnapshot(v interface{}) models.JSON {
+	if v == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return data
+	}
+	for _, key := range auditRelationshipKeys {
+		delete(fields, key)
+	}
+
+	data, err = json.Marshal(fields)
+	if err != nil {
+		return nil
+	}
+
+	snapshot, err := models.TransformSnapshot(data, utils.EncryptField)
+	if err != nil {
+		log.Printf("Failed to encrypt audit snapshot: %v", err)
+		return nil
+	}
+	return snapshot
+}
+
+// decryptAuditSnapshot restores the encrypted fields of a stored snapshot
+func decryptAuditSnapshot(snapshot *models.JSON) {
+	decrypted, err := models.TransformSnapshot(*snapshot, utils.DecryptField)
+	if err != nil {
+		log.Printf("Failed to decrypt audit snapshot: %v", err)
+	}
+	*snapshot = decrypted
+}
+
+// GetAuditLog lists audit rows for houses the caller owns, plus the caller's own actions
+func GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	query := config.DB.Model(&models.AuditLog{}).
+		Where("house_id IN (?) OR actor_id = ?", accessibleHouseIDs(r, models.RoleOwner), currentAdminID(r))
+
+	params := r.URL.Query()
+	for _, filter := range []struct{ param, column string }{
+		{"house_id", "house_id"},
+		{"entity_id", "entity_id"},
+		{"actor_id", "actor_id"},
+	} {
+		if value := params.Get(filter.param); value != "" {
+			id, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidQuery, "Invalid "+filter.param)
+				return
+			}
+			query = query.Where(filter.column+" = ?", uint(id))
+		}
+	}
+	if entityType := params.Get("entity_type"); entityType != "" {
+		query = query.Where("entity_type = ?", entityType)
+	}
+	if action := params.Get("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+
+	for _, bound := range []struct{ param, op string }{{"from", ">="}, {"to", "<="}} {
+		if value := params.Get(bound.param); value != "" {
+			at, err := parseAuditTime(value, bound.param == "to")
+			if err != nil {
+				WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidQuery, "Invalid "+bound.param+" time. Use YYYY-MM-DD or RFC 3339")
+				return
+			}
+			query = query.Where("created_at "+bound.op+" ?", at)
+		}
+	}
+
+	limit := defaultAuditLimit
+	if value := params.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidQuery, "Invalid limit")
+			return
+		}
+		if parsed > maxAuditLimit {
+			parsed = maxAuditLimit
+		}
+		limit = parsed
+	}
+	offset := 0
+	if value := params.Get("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidQuery, "Invalid offset")
+			return
+		}
+		offset = parsed
+	}
+
+	var entries []models.AuditLog
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&entries).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to fetch audit log")
+		return
+	}
+	privacy := newPrivacyFilter(r)
+	for i := range entries {
+		decryptAuditSnapshot(&entries[i].Before)
+		decryptAuditSnapshot(&entries[i].After)
+		privacy.auditEntry(&entries[i])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// parseAuditTime accepts RFC 3339 or a plain date. A plain date used as the upper
+// bound covers the whole day.
+func parseAuditTime(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return t, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gofamtree/utils"
+	"log"
+	"net/http"
+)
+
+// Error codes are part of the API: clients branch on them, so existing codes
+// must never change meaning. Messages are for humans and may be reworded.
+const (
+	// Malformed requests and generic failures
+	ErrCodeInvalidJSON      = "invalid_json"
+	ErrCodeInvalidID        = "invalid_id"
+	ErrCodeInvalidQuery     = "invalid_query"
+	ErrCodeRequestTooLarge  = "request_too_large"
+	ErrCodeValidationFailed = "validation_failed"
+	ErrCodeNotFound         = "not_found"
+	ErrCodeMethodNotAllowed = "method_not_allowed"
+	ErrCodeRateLimited      = "rate_limited"
+	ErrCodeInternal         = "internal_error"
+
+	// Authentication
+	ErrCodeTokenMissing          = "token_missing"
+	ErrCodeTokenInvalid          = "token_invalid"
+	ErrCodeTokenReadOnly         = "token_read_only"
+	ErrCodeInteractiveOnly       = "interactive_login_required"
+	ErrCodeInvalidCredentials    = "invalid_credentials"
+	ErrCodeTooManyAttempts       = "too_many_attempts"
+	ErrCodeAccountDisabled       = "account_disabled"
+	ErrCodeRefreshTokenInvalid   = "refresh_token_invalid"
+	ErrCodeRefreshTokenReused    = "refresh_token_reused"
+	ErrCodeMFATokenInvalid       = "mfa_token_invalid"
+	ErrCodeTwoFactorCodeInvalid  = "two_factor_code_invalid"
+	ErrCodeTwoFactorEnabled      = "two_factor_enabled"
+	ErrCodeTwoFactorNotEnabled   = "two_factor_not_enabled"
+	ErrCodeTwoFactorSetupMissing = "two_factor_setup_required"
+	ErrCodeWeakPassword          = "weak_password"
+	ErrCodeResetTokenInvalid     = "reset_token_invalid"
+	ErrCodeSSONotConfigured      = "sso_not_configured"
+	ErrCodeSSOUnavailable        = "sso_unavailable"
+	ErrCodeSSOStateInvalid       = "sso_state_invalid"
+	ErrCodeSSOFailed             = "sso_failed"
+	ErrCodeIdentityNotLinked     = "identity_not_linked"
+	ErrCodeOriginNotAllowed      = "origin_not_allowed"
+
+	// Authorization
+	ErrCodeInsufficientRole   = "insufficient_role"
+	ErrCodeSuperadminRequired = "superadmin_required"
+	ErrCodeSelfNotAllowed     = "self_not_allowed"
+
+	// Registration and membership
+	ErrCodeRegistrationClosed  = "registration_closed"
+	ErrCodeInviteInvalid       = "invite_invalid"
+	ErrCodeInviteEmailMismatch = "invite_email_mismatch"
+	ErrCodeUsernameTaken       = "username_taken"
+	ErrCodeEmailTaken          = "email_taken"
+	ErrCodeAlreadyMember       = "already_member"
+	ErrCodeLastOwner           = "last_owner"
+	ErrCodeInvalidReassign     = "invalid_reassign_target"
+
+	// Missing resources
+	ErrCodeAdminNotFound      = "admin_not_found"
+	ErrCodeHouseNotFound      = "house_not_found"
+	ErrCodeMemberNotFound     = "member_not_found"
+	ErrCodeInvitationNotFound = "invitation_not_found"
+	ErrCodeInviteNotFound     = "invite_not_found"
+	ErrCodeAPITokenNotFound   = "api_token_not_found"
+	ErrCodeSessionNotFound    = "session_not_found"
+	ErrCodeShareLinkNotFound  = "share_link_not_found"
+	ErrCodePersonNotFound     = "person_not_found"
+	ErrCodePersonNameNotFound = "person_name_not_found"
+	ErrCodeRelationNotFound   = "relation_not_found"
+	ErrCodeEventNotFound      = "event_not_found"
+	ErrCodePlaceNotFound      = "place_not_found"
+
+	// Share links and family data
+	ErrCodeSharePasswordRequired = "share_link_password_required"
+	ErrCodeSharePasswordInvalid  = "share_link_password_invalid"
+	ErrCodeHouseMismatch         = "house_mismatch"
+	ErrCodeRelationExists        = "relation_exists"
+)
+
+// ErrorResponse is the body of every failed request
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+type ErrorBody struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"` // also sent as X-Request-ID
+}
+
+// WriteError answers with the JSON error envelope. Every handler and route
+// middleware reports failures through it.
+func WriteError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	WriteErrorDetails(w, r, status, code, message, nil)
+}
+
+// WriteErrorDetails is WriteError with machine-readable details, such as the
+// field errors of a failed validation
+func WriteErrorDetails(w http.ResponseWriter, r *http.Request, status int, code, message string, details interface{}) {
+	requestID := utils.RequestIDFromContext(r.Context())
+	if status >= http.StatusInternalServerError {
+		// Server-side failures are logged so the request ID a client reports can be traced
+		log.Printf("%s %s failed with %d %s (request %s)", r.Method, r.URL.Path, status, code, requestID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: ErrorBody{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: requestID,
+	}})
+}
@@ -0,0 +1,429 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gofamtree/config"
+	"gofamtree/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type CreateEventInput struct {
+	HouseID uint `json:"house_id"`
+	eventFields
+}
+
+type UpdateEventInput struct {
+	eventFields
+}
+
+// eventFields are the event attributes shared by create and update
+type eventFields struct {
+	EventType    string                  `json:"event_type"`  // birth/baptism/marriage/divorce/...
+	Date         string                  `json:"date"`        // genealogical date (optional)
+	Place        string                  `json:"place"`       // as written in the source (optional)
+	PlaceID      *uint                   `json:"place_id"`    // optional
+	Description  string                  `json:"description"` // optional
+	RelationID   *uint                   `json:"relation_id"` // spouse relation, marriage and divorce only
+	Participants []EventParticipantInput `json:"participants"`
+}
+
+type EventParticipantInput struct {
+	PersonID uint   `json:"person_id"`
+	Role     string `json:"role"` // optional, e.g. principal, witness, godparent
+}
+
+// Role given to the spouses a couple event adds from its relation
+const spouseParticipantRole = "spouse"
+
+func (input CreateEventInput) validate(v *validator) {
+	v.requiredID("house_id", input.HouseID)
+	input.eventFields.validate(v)
+}
+
+func (input eventFields) validate(v *validator) {
+	v.required("event_type", input.EventType)
+	v.oneOf("event_type", input.EventType, models.EventTypes...)
+	v.genDate("date", input.Date)
+	v.maxLength("place", input.Place, maxNameLength)
+	v.maxLength("description", input.Description, maxDescriptionLength)
+	if input.PlaceID != nil && *input.PlaceID == 0 {
+		v.add("place_id", "must be a place ID or null")
+	}
+
+	if input.RelationID != nil {
+		if *input.RelationID == 0 {
+			v.add("relation_id", "must be a relation ID or null")
+		} else if input.EventType != "" && !models.CoupleEvent(input.EventType) {
+			v.add("relation_id", "is only allowed for marriage and divorce events")
+		}
+	}
+
+	// A couple event takes its spouses from the relation
+	if len(input.Participants) == 0 && input.RelationID == nil {
+		v.add("participants", "must list at least one person")
+	}
+	seen := map[uint]bool{}
+	for i, participant := range input.Participants {
+		field := "participants[" + strconv.Itoa(i) + "]"
+		v.requiredID(field+".person_id", participant.PersonID)
+		v.maxLength(field+".role", participant.Role, maxLabelLength)
+		if participant.PersonID != 0 && seen[participant.PersonID] {
+			v.add(field+".person_id", "is listed more than once")
+		}
+		seen[participant.PersonID] = true
+	}
+}
+
+// eventParticipants checks that the participants and the spouse relation of an
+// event belong to its house, and returns the participant rows. The spouses of
+// the relation are added when they are not listed.
+func eventParticipants(w http.ResponseWriter, r *http.Request, houseID uint, input eventFields) ([]models.EventParticipant, bool) {
+	participants := make([]models.EventParticipant, 0, len(input.Participants)+2)
+	listed := map[uint]bool{}
+	for _, participant := range input.Participants {
+		participants = append(participants, models.EventParticipant{PersonID: participant.PersonID, Role: participant.Role})
+		listed[participant.PersonID] = true
+	}
+
+	if input.RelationID != nil {
+		var relation models.Relation
+		if err := config.DB.First(&relation, *input.RelationID).Error; err != nil {
+			WriteError(w, r, http.StatusBadRequest, ErrCodeRelationNotFound, "Relation not found")
+			return nil, false
+		}
+		if relation.HouseID != houseID {
+			WriteError(w, r, http.StatusBadRequest, ErrCodeHouseMismatch, "The relation must belong to the event's house")
+			return nil, false
+		}
+		if relation.RelationType != "spouse" {
+			WriteErrorDetails(w, r, http.StatusUnprocessableEntity, ErrCodeValidationFailed, "Validation failed",
+				[]FieldError{{Field: "relation_id", Message: "must be a spouse relation"}})
+			return nil, false
+		}
+		for _, spouse := range []uint{relation.PersonID, relation.RelatedToID} {
+			if !listed[spouse] {
+				participants = append(participants, models.EventParticipant{PersonID: spouse, Role: spouseParticipantRole})
+				listed[spouse] = true
+			}
+		}
+	}
+
+	ids := make([]uint, 0, len(participants))
+	for _, participant := range participants {
+		ids = append(ids, participant.PersonID)
+	}
+	var persons []models.Person
+	if err := config.DB.Select("id", "house_id").Where("id IN ?", ids).Find(&persons).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to fetch persons")
+		return nil, false
+	}
+	if len(persons) != len(ids) {
+		WriteError(w, r, http.StatusBadRequest, ErrCodePersonNotFound, "Participant not found")
+		return nil, false
+	}
+	for _, person := range persons {
+		if person.HouseID != houseID {
+			WriteError(w, r, http.StatusBadRequest, ErrCodeHouseMismatch, "All participants must belong to the event's house")
+			return nil, false
+		}
+	}
+	return participants, true
+}
+
+// eventsWithParticipants loads events with their participants, ordered by date
+// with undated events last
+func eventsWithParticipants(query *gorm.DB) ([]models.Event, error) {
+	var events []models.Event
+	err := query.Preload("Participants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("person_id")
+	}).Preload("Participants.Person").Preload("Location").Order("date_sort NULLS LAST, id").Find(&events).Error
+	return events, err
+}
+
+// personEvents loads the events a person takes part in
+func personEvents(personID uint) ([]models.Event, error) {
+	return eventsWithParticipants(config.DB.
+		Where("id IN (?)", config.DB.Model(&models.EventParticipant{}).Select("event_id").Where("person_id = ?", personID)))
+}
+
+// parseEventID extracts the ID from /events/{id}
+func parseEventID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	path := strings.TrimPrefix(r.URL.Path, "/events/")
+	id, err := strconv.ParseUint(path, 10, 32)
+	if err != nil {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid event ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func CreateEvent(w http.ResponseWriter, r *http.Request) {
+	var input CreateEventInput
+	if !decodeInput(w, r, &input) {
+		return
+	}
+
+	// Validate that house exists and the caller may edit it
+	role := houseRole(r, input.HouseID)
+	if role == "" {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeHouseNotFound, "House not found")
+		return
+	}
+	if !hasRole(role, models.RoleEditor) {
+		WriteError(w, r, http.StatusForbidden, ErrCodeInsufficientRole, "Requires editor role on this house")
+		return
+	}
+
+	participants, ok := eventParticipants(w, r, input.HouseID, input.eventFields)
+	if !ok || !checkPlace(w, r, input.HouseID, input.PlaceID) {
+		return
+	}
+
+	event := models.Event{
+		HouseID:      input.HouseID,
+		EventType:    input.EventType,
+		Date:         parseOptionalDate(input.Date),
+		Place:        input.Place,
+		PlaceID:      input.PlaceID,
+		Description:  input.Description,
+		RelationID:   input.RelationID,
+		CreatedAt:    time.Now(),
+		Participants: participants,
+	}
+
+	if err := config.DB.Create(&event).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to create event")
+		return
+	}
+	recordAudit(r, event.HouseID, models.AuditCreate, "event", event.ID, nil, event)
+
+	respondWithEvent(w, r, event.ID, http.StatusCreated)
+}
+
+func GetEvents(w http.ResponseWriter, r *http.Request) {
+	// Only events in houses the caller can reach
+	query := config.DB.Where("house_id IN (?)", accessibleHouseIDs(r, models.RoleViewer))
+
+	params := r.URL.Query()
+	for _, filter := range []string{"house_id", "person_id", "place_id"} {
+		value := params.Get(filter)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidQuery, "Invalid "+filter)
+			return
+		}
+		switch filter {
+		case "house_id":
+			query = query.Where("house_id = ?", uint(id))
+		case "person_id":
+			query = query.Where("id IN (?)", config.DB.Model(&models.EventParticipant{}).Select("event_id").Where("person_id = ?", uint(id)))
+		case "place_id":
+			// The place or anywhere within it
+			query = query.Where("place_id IN (?)", placeSubtree(uint(id)))
+		}
+	}
+	if eventType := params.Get("event_type"); eventType != "" {
+		query = query.Where("event_type = ?", eventType)
+	}
+
+	events, err := eventsWithParticipants(query)
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to fetch events")
+		return
+	}
+	newPrivacyFilter(r).events(events)
+
+	// Places of withheld events must not show through the filter either
+	if params.Get("place_id") != "" {
+		visible := events[:0]
+		for _, event := range events {
+			if !event.Redacted {
+				visible = append(visible, event)
+			}
+		}
+		events = visible
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+func GetEvent(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseEventID(w, r)
+	if !ok {
+		return
+	}
+
+	var event models.Event
+	if err := config.DB.Where("house_id IN (?)", accessibleHouseIDs(r, models.RoleViewer)).Select("id").First(&event, id).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodeEventNotFound, "Event not found")
+		return
+	}
+
+	respondWithEvent(w, r, event.ID, http.StatusOK)
+}
+
+func UpdateEvent(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseEventID(w, r)
+	if !ok {
+		return
+	}
+
+	var input UpdateEventInput
+	if !decodeInput(w, r, &input) {
+		return
+	}
+
+	var event models.Event
+	if err := config.DB.Where("house_id IN (?)", accessibleHouseIDs(r, models.RoleViewer)).
+		Preload("Participants").First(&event, id).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodeEventNotFound, "Event not found")
+		return
+	}
+
+	if !requireHouseRole(w, r, event.HouseID, models.RoleEditor) {
+		return
+	}
+
+	participants, ok := eventParticipants(w, r, event.HouseID, input.eventFields)
+	if !ok || !checkPlace(w, r, event.HouseID, input.PlaceID) {
+		return
+	}
+
+	// Callers who only see this event redacted cannot clear what they were never shown
+	privacy := newPrivacyFilter(r)
+	keepPrivate := !privacy.canSeeEvent(&event)
+
+	before := event
+	applyEventUpdate(&event, input.eventFields, participants, keepPrivate)
+
+	// Nor may they reveal the event by removing its living participants
+	if keepPrivate && privacy.canSeeEvent(&event) {
+		WriteError(w, r, http.StatusForbidden, ErrCodeInsufficientRole, "Requires the house's privacy role to remove the living participants of this event")
+		return
+	}
+
+	// The participant list is replaced as a whole
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Participants").Save(&event).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("event_id = ?", event.ID).Delete(&models.EventParticipant{}).Error; err != nil {
+			return err
+		}
+		for i := range participants {
+			participants[i].EventID = event.ID
+		}
+		return tx.Create(&participants).Error
+	})
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to update event")
+		return
+	}
+	recordAudit(r, event.HouseID, models.AuditUpdate, "event", event.ID, before, event)
+
+	respondWithEvent(w, r, event.ID, http.StatusOK)
+}
+
+// applyEventUpdate copies an update and its participants onto an event. With
+// keepPrivate, private fields left empty keep their stored values.
+func applyEventUpdate(event *models.Event, input eventFields, participants []models.EventParticipant, keepPrivate bool) {
+	event.EventType = input.EventType
+	event.RelationID = input.RelationID
+	if !keepPrivate || input.Date != "" {
+		event.Date = parseOptionalDate(input.Date)
+	}
+	if !keepPrivate || input.Place != "" {
+		event.Place = input.Place
+	}
+	if !keepPrivate || input.PlaceID != nil {
+		event.PlaceID = input.PlaceID
+	}
+	if !keepPrivate || input.Description != "" {
+		event.Description = input.Description
+	}
+	event.Participants = participants
+}
+
+func DeleteEvent(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseEventID(w, r)
+	if !ok {
+		return
+	}
+
+	var event models.Event
+	if err := config.DB.Where("house_id IN (?)", accessibleHouseIDs(r, models.RoleViewer)).
+		Preload("Participants").First(&event, id).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodeEventNotFound, "Event not found")
+		return
+	}
+
+	if !requireHouseRole(w, r, event.HouseID, models.RoleEditor) {
+		return
+	}
+
+	config.DB.Where("event_id = ?", event.ID).Delete(&models.EventParticipant{})
+	if err := config.DB.Delete(&event).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to delete event")
+		return
+	}
+	recordAudit(r, event.HouseID, models.AuditDelete, "event", event.ID, event, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Event deleted successfully",
+	})
+}
+
+// respondWithEvent writes one event with its participants
+func respondWithEvent(w http.ResponseWriter, r *http.Request, id uint, status int) {
+	events, err := eventsWithParticipants(config.DB.Where("id = ?", id))
+	if err != nil || len(events) == 0 {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to fetch event")
+		return
+	}
+	newPrivacyFilter(r).event(&events[0])
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(events[0])
+}
+
+// deletePersonEvents removes a person from the events they take part in, and
+// deletes the events no one is left in. The deleted events come back with
+// their participants for the audit log, which the caller records once tx commits.
+func deletePersonEvents(tx *gorm.DB, personID uint) ([]models.Event, error) {
+	var eventIDs []uint
+	if err := tx.Model(&models.EventParticipant{}).Where("person_id = ?", personID).Pluck("event_id", &eventIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(eventIDs) == 0 {
+		return nil, nil
+	}
+
+	// The events only this person takes part in, loaded with their participants
+	// first so the audit snapshot keeps them and stays redacted
+	var orphans []models.Event
+	if err := tx.Preload("Participants").Where("id IN ?", eventIDs).
+		Where("NOT EXISTS (SELECT 1 FROM event_participants WHERE event_participants.event_id = events.id AND event_participants.person_id <> ?)", personID).
+		Find(&orphans).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("person_id = ?", personID).Delete(&models.EventParticipant{}).Error; err != nil {
+		return nil, err
+	}
+	for _, event := range orphans {
+		if err := tx.Delete(&models.Event{}, event.ID).Error; err != nil {
+			return nil, err
+		}
+	}
+	return orphans, nil
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"gofamtree/models"
+	"testing"
+	"time"
+)
+
+// TestUpdateEventCannotRevealLivingParticipants covers callers below a
+// house's privacy_min_role: they may edit an event they see redacted, but not
+// change its participants so that they see it in full.
+func TestUpdateEventCannotRevealLivingParticipants(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	privacy := &privacyFilter{now: now, policies: map[uint]housePrivacy{
+		1: {fullAccess: false, livingYears: models.DefaultPrivacyLivingYears},
+	}}
+	born, _ := models.ParseGenDate("1990")
+	died, _ := models.ParseGenDate("1950")
+	living := &models.Person{ID: 7, HouseID: 1, DOB: &born}
+	otherLiving := &models.Person{ID: 8, HouseID: 1, DOB: &born}
+	deceased := &models.Person{ID: 9, HouseID: 1, DOD: &died}
+	participant := func(person *models.Person) models.EventParticipant {
+		return models.EventParticipant{EventID: 3, PersonID: person.ID, Person: person}
+	}
+
+	tests := []struct {
+		name         string
+		participants []models.EventParticipant
+		reveals      bool
+	}{
+		{"same participants", []models.EventParticipant{participant(living), participant(deceased)}, false},
+		{"living participant swapped", []models.EventParticipant{participant(otherLiving)}, false},
+		{"living participant removed", []models.EventParticipant{participant(deceased)}, true},
+		{"no participants", []models.EventParticipant{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			date, _ := models.ParseGenDate("2015-06-01")
+			event := models.Event{ID: 3, HouseID: 1, EventType: "marriage", Date: &date, Description: "private",
+				Participants: []models.EventParticipant{participant(living), participant(deceased)}}
+			keepPrivate := !privacy.canSeeEvent(&event)
+			if !keepPrivate {
+				t.Fatal("an event with a living participant is visible below the privacy role")
+			}
+
+			applyEventUpdate(&event, eventFields{EventType: "marriage"}, tt.participants, keepPrivate)
+			if got := privacy.canSeeEvent(&event); got != tt.reveals {
+				t.Errorf("update reveals the event = %v, want %v", got, tt.reveals)
+			}
+			if event.Description != "private" || event.Date == nil {
+				t.Errorf("private fields were cleared: date %v, description %q", event.Date, event.Description)
+			}
+		})
+	}
+}
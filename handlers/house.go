@@ -8,42 +8,90 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type CreateHouseInput struct {
-	Name      string `json:"name"`
-	CreatedBy uint   `json:"created_by"` // Admin ID
+	Name               string  `json:"name"`
+	PrivacyMinRole     *string `json:"privacy_min_role"`     // owner/editor/viewer (optional)
+	PrivacyLivingYears *int    `json:"privacy_living_years"` // optional
 }
 
 type UpdateHouseInput struct {
-	Name string `json:"name"`
+	Name               string  `json:"name"`
+	PrivacyMinRole     *string `json:"privacy_min_role"`     // owner only
+	PrivacyLivingYears *int    `json:"privacy_living_years"` // owner only
 }
 
-func CreateHouse(w http.ResponseWriter, r *http.Request) {
-	var input CreateHouseInput
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
-		return
+func (input CreateHouseInput) validate(v *validator) {
+	validateHouseFields(v, input.Name, input.PrivacyMinRole, input.PrivacyLivingYears)
+}
+
+func (input UpdateHouseInput) validate(v *validator) {
+	validateHouseFields(v, input.Name, input.PrivacyMinRole, input.PrivacyLivingYears)
+}
+
+func validateHouseFields(v *validator, name string, minRole *string, livingYears *int) {
+	v.required("name", name)
+	v.maxLength("name", name, maxNameLength)
+	if minRole != nil {
+		v.required("privacy_min_role", *minRole)
+		v.oneOf("privacy_min_role", *minRole, models.RoleOwner, models.RoleEditor, models.RoleViewer)
 	}
+	if livingYears != nil {
+		v.between("privacy_living_years", *livingYears, 1, 150)
+	}
+}
+
+// applyPrivacyPolicy copies optional privacy settings onto a house
+func applyPrivacyPolicy(house *models.House, minRole *string, livingYears *int) {
+	if minRole != nil {
+		house.PrivacyMinRole = *minRole
+	}
+	if livingYears != nil {
+		house.PrivacyLivingYears = *livingYears
+	}
+}
 
-	// Validate that admin exists
-	var admin models.Admin
-	if err := config.DB.First(&admin, input.CreatedBy).Error; err != nil {
-		http.Error(w, "Admin not found", http.StatusBadRequest)
+func CreateHouse(w http.ResponseWriter, r *http.Request) {
+	var input CreateHouseInput
+	if !decodeInput(w, r, &input) {
 		return
 	}
 
+	// The house always belongs to the authenticated admin
 	house := models.House{
-		Name:      input.Name,
-		CreatedBy: input.CreatedBy,
-		CreatedAt: time.Now(),
+		Name:               input.Name,
+		CreatedBy:          currentAdminID(r),
+		CreatedAt:          time.Now(),
+		PrivacyMinRole:     models.DefaultPrivacyMinRole,
+		PrivacyLivingYears: models.DefaultPrivacyLivingYears,
 	}
-
-	if err := config.DB.Create(&house).Error; err != nil {
-		http.Error(w, "Failed to create house", http.StatusInternalServerError)
+	applyPrivacyPolicy(&house, input.PrivacyMinRole, input.PrivacyLivingYears)
+
+	// The creator becomes the first owner of the house
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&house).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		return tx.Create(&models.HouseMember{
+			HouseID:    house.ID,
+			AdminID:    house.CreatedBy,
+			Role:       models.RoleOwner,
+			Status:     models.MemberStatusActive,
+			CreatedAt:  now,
+			AcceptedAt: &now,
+		}).Error
+	})
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to create house")
 		return
 	}
 
+	recordAudit(r, house.ID, models.AuditCreate, "house", house.ID, nil, house)
+
 	// Load the admin relationship
 	config.DB.Preload("Admin").First(&house, house.ID)
 
@@ -55,8 +103,8 @@ func CreateHouse(w http.ResponseWriter, r *http.Request) {
 func GetHouses(w http.ResponseWriter, r *http.Request) {
 	var houses []models.House
 	
-	if err := config.DB.Preload("Admin").Find(&houses).Error; err != nil {
-		http.Error(w, "Failed to fetch houses", http.StatusInternalServerError)
+	if err := config.DB.Where("id IN (?)", accessibleHouseIDs(r, models.RoleViewer)).Preload("Admin").Find(&houses).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to fetch houses")
 		return
 	}
 
@@ -69,15 +117,18 @@ func GetHouse(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/houses/")
 	id, err := strconv.ParseUint(path, 10, 32)
 	if err != nil {
-		http.Error(w, "Invalid house ID", http.StatusBadRequest)
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid house ID")
 		return
 	}
 
 	var house models.House
-	if err := config.DB.Preload("Admin").Preload("Persons").First(&house, uint(id)).Error; err != nil {
-		http.Error(w, "House not found", http.StatusNotFound)
+	if err := config.DB.Where("id IN (?)", accessibleHouseIDs(r, models.RoleViewer)).
+		Preload("Admin").Preload("Persons").Preload("Members", "status = ?", models.MemberStatusActive).Preload("Members.Admin").
+		First(&house, uint(id)).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodeHouseNotFound, "House not found")
 		return
 	}
+	newPrivacyFilter(r).persons(house.Persons)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(house)
@@ -88,27 +139,40 @@ func UpdateHouse(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/houses/")
 	id, err := strconv.ParseUint(path, 10, 32)
 	if err != nil {
-		http.Error(w, "Invalid house ID", http.StatusBadRequest)
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid house ID")
 		return
 	}
 
 	var input UpdateHouseInput
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
+	if !decodeInput(w, r, &input) {
 		return
 	}
 
 	var house models.House
-	if err := config.DB.First(&house, uint(id)).Error; err != nil {
-		http.Error(w, "House not found", http.StatusNotFound)
+	if err := config.DB.Where("id IN (?)", accessibleHouseIDs(r, models.RoleViewer)).First(&house, uint(id)).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodeHouseNotFound, "House not found")
 		return
 	}
 
+	if !requireHouseRole(w, r, house.ID, models.RoleEditor) {
+		return
+	}
+
+	// Only owners may change who sees living persons
+	if input.PrivacyMinRole != nil || input.PrivacyLivingYears != nil {
+		if !requireHouseRole(w, r, house.ID, models.RoleOwner) {
+			return
+		}
+	}
+
+	before := house
 	house.Name = input.Name
+	applyPrivacyPolicy(&house, input.PrivacyMinRole, input.PrivacyLivingYears)
 	if err := config.DB.Save(&house).Error; err != nil {
-		http.Error(w, "Failed to update house", http.StatusInternalServerError)
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to update house")
 		return
 	}
+	recordAudit(r, house.ID, models.AuditUpdate, "house", house.ID, before, house)
 
 	// Load relationships
 	config.DB.Preload("Admin").First(&house, house.ID)
@@ -122,22 +186,61 @@ func DeleteHouse(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/houses/")
 	id, err := strconv.ParseUint(path, 10, 32)
 	if err != nil {
-		http.Error(w, "Invalid house ID", http.StatusBadRequest)
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid house ID")
 		return
 	}
 
 	var house models.House
-	if err := config.DB.First(&house, uint(id)).Error; err != nil {
-		http.Error(w, "House not found", http.StatusNotFound)
+	if err := config.DB.Where("id IN (?)", accessibleHouseIDs(r, models.RoleViewer)).First(&house, uint(id)).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodeHouseNotFound, "House not found")
+		return
+	}
+
+	// Only owners may delete a house
+	if !requireHouseRole(w, r, house.ID, models.RoleOwner) {
 		return
 	}
 
-	// Delete all relations in this house first
-	config.DB.Where("house_id = ?", uint(id)).Delete(&models.Relation{})
-	// Delete all persons in this house
-	config.DB.Where("house_id = ?", uint(id)).Delete(&models.Person{})
-	// Delete the house
-	config.DB.Delete(&house)
+	// Delete everything in the house, or nothing if any step fails
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Memberships and share links first
+		if err := tx.Where("house_id = ?", house.ID).Delete(&models.HouseMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("house_id = ?", house.ID).Delete(&models.ShareLink{}).Error; err != nil {
+			return err
+		}
+		// Events with their participants, then relations
+		if err := tx.Where("event_id IN (?)", tx.Model(&models.Event{}).Select("id").Where("house_id = ?", house.ID)).Delete(&models.EventParticipant{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("house_id = ?", house.ID).Delete(&models.Event{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("house_id = ?", house.ID).Delete(&models.Relation{}).Error; err != nil {
+			return err
+		}
+		// Persons with their names
+		if err := tx.Where("person_id IN (?)", tx.Model(&models.Person{}).Select("id").Where("house_id = ?", house.ID)).Delete(&models.PersonName{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("house_id = ?", house.ID).Delete(&models.Person{}).Error; err != nil {
+			return err
+		}
+		// Places with their other names
+		if err := tx.Where("place_id IN (?)", tx.Model(&models.Place{}).Select("id").Where("house_id = ?", house.ID)).Delete(&models.PlaceName{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("house_id = ?", house.ID).Delete(&models.Place{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&house).Error
+	})
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to delete house")
+		return
+	}
+	recordAudit(r, house.ID, models.AuditDelete, "house", house.ID, house, nil)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"gofamtree/config"
+	"gofamtree/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// loginAttempts tracks recent failed logins for one username or client IP
+type loginAttempts struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// loginGuard slows down and then locks out repeated failed logins. Usernames that
+// do not exist are tracked exactly like real ones, so lockouts reveal nothing.
+type loginGuard struct {
+	mu       sync.Mutex
+	attempts map[string]*loginAttempts
+}
+
+var loginLimiter = &loginGuard{attempts: make(map[string]*loginAttempts)}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+func usernameKey(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// wait returns how long the key must wait before its next attempt
+func (g *loginGuard) wait(key string, now time.Time) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	attempts := g.current(key, now)
+	if attempts == nil {
+		return 0
+	}
+	if now.Before(attempts.lockedUntil) {
+		return attempts.lockedUntil.Sub(now)
+	}
+	if next := attempts.lastFailure.Add(progressiveDelay(attempts.failures)); now.Before(next) {
+		return next.Sub(now)
+	}
+	return 0
+}
+
+// fail records a failed attempt and reports whether it locked the key out
+func (g *loginGuard) fail(key string, maxFailures int, now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Keep memory bounded when many different usernames or IPs are tried
+	if len(g.attempts) > 10000 {
+		for k := range g.attempts {
+			g.current(k, now)
+		}
+	}
+
+	attempts := g.current(key, now)
+	if attempts == nil {
+		attempts = &loginAttempts{}
+		g.attempts[key] = attempts
+	}
+
+	attempts.failures++
+	attempts.lastFailure = now
+	if attempts.failures >= maxFailures {
+		attempts.failures = 0
+		attempts.lockedUntil = now.Add(config.LoginLockout)
+		return true
+	}
+	return false
+}
+
+func (g *loginGuard) reset(key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.attempts, key)
+}
+
+// current returns the entry for key, dropping it once it has gone stale
+func (g *loginGuard) current(key string, now time.Time) *loginAttempts {
+	attempts, ok := g.attempts[key]
+	if !ok {
+		return nil
+	}
+	if !now.Before(attempts.lockedUntil) && now.Sub(attempts.lastFailure) > config.LoginFailureWindow {
+		delete(g.attempts, key)
+		return nil
+	}
+	return attempts
+}
+
+// progressiveDelay doubles the wait after every failure beyond the second, up to LoginMaxDelay
+func progressiveDelay(failures int) time.Duration {
+	if failures < 3 {
+		return 0
+	}
+	shift := failures - 3
+	if shift > 16 {
+		return config.LoginMaxDelay
+	}
+	delay := time.Second << shift
+	if delay > config.LoginMaxDelay {
+		return config.LoginMaxDelay
+	}
+	return delay
+}
+
+// passwordDummyHash is compared against when the username is unknown, so that
+// unknown usernames take as long as wrong passwords
+func passwordDummyHash() string {
+	dummyHashOnce.Do(func() {
+		token, _ := utils.RandomToken()
+		dummyHash, _ = utils.HashPassword(token)
+	})
+	return dummyHash
+}
+
+func tooManyAttempts(w http.ResponseWriter, r *http.Request, wait time.Duration) {
+	seconds := int(wait.Seconds())
+	if wait > time.Duration(seconds)*time.Second {
+		seconds++
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	WriteError(w, r, http.StatusTooManyRequests, ErrCodeTooManyAttempts, "Too many failed login attempts, try again later")
+}
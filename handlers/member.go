@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"gofamtree/config"
+	"gofamtree/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errLastOwner = errors.New("a house must keep an owner")
+
+type InviteMemberInput struct {
+	Username string `json:"username"`
+	Role     string `json:"role"` // owner/editor/viewer
+}
+
+type UpdateMemberInput struct {
+	Role string `json:"role"`
+}
+
+func (input InviteMemberInput) validate(v *validator) {
+	v.required("username", input.Username)
+	v.maxLength("username", input.Username, maxUsernameLength)
+	validateRole(v, input.Role)
+}
+
+func (input UpdateMemberInput) validate(v *validator) {
+	validateRole(v, input.Role)
+}
+
+func validateRole(v *validator, role string) {
+	v.required("role", role)
+	v.oneOf("role", role, models.RoleOwner, models.RoleEditor, models.RoleViewer)
+}
+
+// parseMemberPath extracts the IDs from /houses/{id}/members[/{admin_id}].
+// adminID is 0 when the path stops at /members.
+func parseMemberPath(path string) (houseID uint, adminID uint, ok bool) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(path, "/houses/"), "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[1] != "members" {
+		return 0, 0, false
+	}
+
+	id, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+	if len(parts) == 2 {
+		return uint(id), 0, true
+	}
+
+	member, err := strconv.ParseUint(parts[2], 10, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+	return uint(id), uint(member), true
+}
+
+// keepOwner locks the active owners of a house for the rest of tx and fails
+// with errLastOwner when member is the only one, so two owners cannot demote
+// or remove each other at the same time
+func keepOwner(tx *gorm.DB, member models.HouseMember) error {
+	if member.Role != models.RoleOwner || member.Status != models.MemberStatusActive {
+		return nil
+	}
+	var owners []models.HouseMember
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+		Where("house_id = ? AND role = ? AND status = ?", member.HouseID, models.RoleOwner, models.MemberStatusActive).
+		Find(&owners).Error; err != nil {
+		return err
+	}
+	if len(owners) <= 1 {
+		return errLastOwner
+	}
+	return nil
+}
+
+func GetHouseMembers(w http.ResponseWriter, r *http.Request) {
+	houseID, _, ok := parseMemberPath(r.URL.Path)
+	if !ok {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid house ID")
+		return
+	}
+
+	if !requireHouseRole(w, r, houseID, models.RoleViewer) {
+		return
+	}
+
+	var members []models.HouseMember
+	if err := config.DB.Where("house_id = ?", houseID).Preload("Admin").
+		Order("id").Find(&members).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to fetch members")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(members)
+}
+
+func InviteHouseMember(w http.ResponseWriter, r *http.Request) {
+	houseID, _, ok := parseMemberPath(r.URL.Path)
+	if !ok {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid house ID")
+		return
+	}
+
+	var input InviteMemberInput
+	if !decodeInput(w, r, &input) {
+		return
+	}
+
+	// Only owners manage membership
+	if !requireHouseRole(w, r, houseID, models.RoleOwner) {
+		return
+	}
+
+	var invitee models.Admin
+	if err := config.DB.Where("username = ?", input.Username).First(&invitee).Error; err != nil {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeAdminNotFound, "Admin not found")
+		return
+	}
+
+	var existing models.HouseMember
+	if err := config.DB.Where("house_id = ? AND admin_id = ?", houseID, invitee.ID).First(&existing).Error; err == nil {
+		WriteError(w, r, http.StatusConflict, ErrCodeAlreadyMember, "Admin is already a member or invited")
+		return
+	}
+
+	inviter := currentAdminID(r)
+	member := models.HouseMember{
+		HouseID:   houseID,
+		AdminID:   invitee.ID,
+		Role:      input.Role,
+		Status:    models.MemberStatusPending,
+		InvitedBy: &inviter,
+		CreatedAt: time.Now(),
+	}
+
+	if err := config.DB.Create(&member).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to invite member")
+		return
+	}
+	recordAudit(r, houseID, models.AuditCreate, "house_member", member.ID, nil, member)
+
+	config.DB.Preload("Admin").First(&member, member.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(member)
+}
+
+func UpdateHouseMember(w http.ResponseWriter, r *http.Request) {
+	houseID, adminID, ok := parseMemberPath(r.URL.Path)
+	if !ok || adminID == 0 {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid member path")
+		return
+	}
+
+	var input UpdateMemberInput
+	if !decodeInput(w, r, &input) {
+		return
+	}
+
+	if !requireHouseRole(w, r, houseID, models.RoleOwner) {
+		return
+	}
+
+	var member models.HouseMember
+	if err := config.DB.Where("house_id = ? AND admin_id = ?", houseID, adminID).First(&member).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodeMemberNotFound, "Member not found")
+		return
+	}
+
+	before := member
+	member.Role = input.Role
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// A house must always keep at least one owner
+		if input.Role != models.RoleOwner {
+			if err := keepOwner(tx, before); err != nil {
+				return err
+			}
+		}
+		return tx.Save(&member).Error
+	})
+	if err == errLastOwner {
+		WriteError(w, r, http.StatusConflict, ErrCodeLastOwner, "Cannot demote the last owner of a house")
+		return
+	}
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to update member")
+		return
+	}
+	recordAudit(r, houseID, models.AuditUpdate, "house_member", member.ID, before, member)
+
+	config.DB.Preload("Admin").First(&member, member.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(member)
+}
+
+func RemoveHouseMember(w http.ResponseWriter, r *http.Request) {
+	houseID, adminID, ok := parseMemberPath(r.URL.Path)
+	if !ok || adminID == 0 {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid member path")
+		return
+	}
+
+	// Members may leave a house themselves; removing anyone else needs the owner role
+	if adminID == currentAdminID(r) {
+		if !requireHouseRole(w, r, houseID, models.RoleViewer) {
+			return
+		}
+	} else if !requireHouseRole(w, r, houseID, models.RoleOwner) {
+		return
+	}
+
+	var member models.HouseMember
+	if err := config.DB.Where("house_id = ? AND admin_id = ?", houseID, adminID).First(&member).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodeMemberNotFound, "Member not found")
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := keepOwner(tx, member); err != nil {
+			return err
+		}
+		return tx.Delete(&member).Error
+	})
+	if err == errLastOwner {
+		WriteError(w, r, http.StatusConflict, ErrCodeLastOwner, "Cannot remove the last owner of a house")
+		return
+	}
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to remove member")
+		return
+	}
+	recordAudit(r, houseID, models.AuditDelete, "house_member", member.ID, member, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Member removed successfully",
+	})
+}
+
+func GetInvitations(w http.ResponseWriter, r *http.Request) {
+	var invitations []models.HouseMember
+	if err := config.DB.Where("admin_id = ? AND status = ?", currentAdminID(r), models.MemberStatusPending).
+		Preload("House").Find(&invitations).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to fetch invitations")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invitations)
+}
+
+func AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from URL path /invitations/{id}/accept
+	path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/invitations/"), "/accept")
+	id, err := strconv.ParseUint(path, 10, 32)
+	if err != nil {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid invitation ID")
+		return
+	}
+
+	var member models.HouseMember
+	if err := config.DB.Where("admin_id = ? AND status = ?", currentAdminID(r), models.MemberStatusPending).
+		First(&member, uint(id)).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodeInvitationNotFound, "Invitation not found")
+		return
+	}
+
+	now := time.Now()
+	before := member
+	member.Status = models.MemberStatusActive
+	member.AcceptedAt = &now
+	if err := config.DB.Save(&member).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to accept invitation")
+		return
+	}
+	recordAudit(r, member.HouseID, models.AuditUpdate, "house_member", member.ID, before, member)
+
+	config.DB.Preload("House").First(&member, member.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(member)
+}
+
+func DeclineInvitation(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/invitations/")
+	id, err := strconv.ParseUint(path, 10, 32)
+	if err != nil {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid invitation ID")
+		return
+	}
+
+	var member models.HouseMember
+	if err := config.DB.Where("admin_id = ? AND status = ?", currentAdminID(r), models.MemberStatusPending).
+		First(&member, uint(id)).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodeInvitationNotFound, "Invitation not found")
+		return
+	}
+
+	if err := config.DB.Delete(&member).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to decline invitation")
+		return
+	}
+	recordAudit(r, member.HouseID, models.AuditDelete, "house_member", member.ID, member, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Invitation declined",
+	})
+}
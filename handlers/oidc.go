@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"gofamtree/config"
+	"gofamtree/models"
+	"gofamtree/utils"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// oidcStateTTL is how long a user has to finish signing in at the provider
+const oidcStateTTL = 10 * time.Minute
+
+// oidcStateCookie ties a pending login to the browser that started it, so a
+// callback URL from someone else's login cannot sign this browser in
+const oidcStateCookie = "gft_oidc_state"
+
+var errOIDCNoAccount = errors.New("no admin account is linked to this identity")
+
+// oidcLogin is what the callback needs to finish a login started by OIDCLogin
+type oidcLogin struct {
+	nonce        string
+	codeVerifier string
+	expiresAt    time.Time
+}
+
+// oidcStates holds pending logins keyed by their state parameter
+var oidcStates = struct {
+	sync.Mutex
+	logins map[string]oidcLogin
+}{logins: map[string]oidcLogin{}}
+
+func saveOIDCLogin(state string, login oidcLogin) {
+	oidcStates.Lock()
+	defer oidcStates.Unlock()
+	now := time.Now()
+	for key, pending := range oidcStates.logins {
+		if now.After(pending.expiresAt) {
+			delete(oidcStates.logins, key)
+		}
+	}
+	oidcStates.logins[state] = login
+}
+
+// takeOIDCLogin returns a pending login once; a state can never be replayed
+func takeOIDCLogin(state string) (oidcLogin, bool) {
+	oidcStates.Lock()
+	defer oidcStates.Unlock()
+	login, ok := oidcStates.logins[state]
+	delete(oidcStates.logins, state)
+	return login, ok && time.Now().Before(login.expiresAt)
+}
+
+// OIDCLogin starts an authorization-code login with PKCE by redirecting to the provider
+func OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if utils.OIDC == nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodeSSONotConfigured, "Single sign-on is not configured")
+		return
+	}
+
+	state, errState := utils.RandomToken()
+	nonce, errNonce := utils.RandomToken()
+	verifier, challenge, errPKCE := utils.NewPKCEVerifier()
+	if errState != nil || errNonce != nil || errPKCE != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeSSOFailed, "Failed to start sign-in")
+		return
+	}
+
+	authURL, err := utils.OIDC.AuthCodeURL(state, nonce, challenge)
+	if err != nil {
+		log.Printf("OIDC login failed: %v", err)
+		WriteError(w, r, http.StatusBadGateway, ErrCodeSSOUnavailable, "Identity provider unavailable")
+		return
+	}
+
+	saveOIDCLogin(state, oidcLogin{nonce: nonce, codeVerifier: verifier, expiresAt: time.Now().Add(oidcStateTTL)})
+	setOIDCStateCookie(w, state, int(oidcStateTTL.Seconds()))
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallback finishes the login: it exchanges the code, verifies the ID token
+// and signs in the linked admin, creating or linking one on first sign-in
+func OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if utils.OIDC == nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodeSSONotConfigured, "Single sign-on is not configured")
+		return
+	}
+
+	params := r.URL.Query()
+	if providerError := params.Get("error"); providerError != "" {
+		log.Printf("OIDC provider returned error: %q", providerError)
+		WriteError(w, r, http.StatusUnauthorized, ErrCodeSSOFailed, "Sign-in failed")
+		return
+	}
+
+	state := params.Get("state")
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeSSOStateInvalid, "Invalid or expired sign-in state")
+		return
+	}
+	setOIDCStateCookie(w, "", -1)
+
+	login, ok := takeOIDCLogin(state)
+	if !ok {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeSSOStateInvalid, "Invalid or expired sign-in state")
+		return
+	}
+
+	idToken, err := utils.OIDC.Exchange(params.Get("code"), login.codeVerifier)
+	if err != nil {
+		log.Printf("OIDC code exchange failed: %v", err)
+		WriteError(w, r, http.StatusUnauthorized, ErrCodeSSOFailed, "Sign-in failed")
+		return
+	}
+	claims, err := utils.OIDC.VerifyIDToken(idToken, login.nonce)
+	if err != nil {
+		log.Printf("OIDC ID token rejected: %v", err)
+		WriteError(w, r, http.StatusUnauthorized, ErrCodeSSOFailed, "Sign-in failed")
+		return
+	}
+
+	admin, err := findOIDCAdmin(claims)
+	if err == errOIDCNoAccount {
+		WriteError(w, r, http.StatusForbidden, ErrCodeIdentityNotLinked, "No admin account is linked to this identity")
+		return
+	}
+	if err != nil {
+		log.Printf("OIDC sign-in failed: %v", err)
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeSSOFailed, "Sign-in failed")
+		return
+	}
+
+	if admin.DisabledAt != nil {
+		WriteError(w, r, http.StatusForbidden, ErrCodeAccountDisabled, "Account is disabled")
+		return
+	}
+
+	if config.OIDCPostLoginRedirect != "" {
+		redirectWithLoginResult(w, r, admin)
+		return
+	}
+	if admin.TOTPEnabled {
+		respondWithMFAChallenge(w, r, admin)
+		return
+	}
+	respondWithLoginToken(w, r, admin)
+}
+
+// setOIDCStateCookie stores the state of a pending login, or clears it when
+// maxAge is negative
+func setOIDCStateCookie(w http.ResponseWriter, state string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/admin/oidc",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(config.OIDCRedirectURL, "https://"),
+		// Lax still sends the cookie on the provider's redirect back to us
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// redirectWithLoginResult hands the token to the front-end in the URL fragment,
+// which browsers never send to servers
+func redirectWithLoginResult(w http.ResponseWriter, r *http.Request, admin models.Admin) {
+	result := url.Values{}
+	var expiresAt time.Time
+	var err error
+	if admin.TOTPEnabled {
+		var token string
+		token, expiresAt, err = utils.GenerateMFAToken(admin.ID)
+		result.Set("mfa_required", "true")
+		result.Set("mfa_token", token)
+	} else {
+		var tokens loginTokens
+		tokens, err = startSession(r, admin)
+		expiresAt = tokens.ExpiresAt
+		result.Set("token", tokens.AccessToken)
+		result.Set("token_type", "Bearer")
+		result.Set("refresh_token", tokens.RefreshToken)
+		result.Set("refresh_expires_at", tokens.RefreshExpiresAt.Format(time.RFC3339))
+	}
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to issue token")
+		return
+	}
+	result.Set("expires_at", expiresAt.Format(time.RFC3339))
+
+	http.Redirect(w, r, config.OIDCPostLoginRedirect+"#"+result.Encode(), http.StatusFound)
+}
+
+// findOIDCAdmin is replaced in tests, which run without a database
+var findOIDCAdmin = oidcAdmin
+
+// oidcAdmin finds the admin for a verified identity. An unknown identity is
+// linked to the admin with the same email when both the provider and this
+// installation have verified it, or gets a new admin if OIDC_CREATE_ADMINS is on.
+func oidcAdmin(claims map[string]interface{}) (models.Admin, error) {
+	var admin models.Admin
+	subject := utils.ClaimString(claims, "sub")
+	email := utils.ClaimString(claims, config.OIDCEmailClaim)
+	emailVerified := email != "" && utils.ClaimBool(claims, "email_verified")
+	now := time.Now()
+
+	var identity models.AdminIdentity
+	err := config.DB.Where("issuer = ? AND subject = ?", config.OIDCIssuer, subject).First(&identity).Error
+	if err == nil {
+		config.DB.Model(&identity).Update("last_login_at", now)
+		return admin, config.DB.First(&admin, identity.AdminID).Error
+	}
+	if err != gorm.ErrRecordNotFound {
+		return admin, err
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Serialize with registration so the first admin is created only once
+		if err := tx.Exec("LOCK TABLE admins IN SHARE ROW EXCLUSIVE MODE").Error; err != nil {
+			return err
+		}
+
+		linked := false
+		if emailVerified {
+			err := tx.Where("LOWER(email) = LOWER(?) AND email_verified_at IS NOT NULL", email).First(&admin).Error
+			if err != nil && err != gorm.ErrRecordNotFound {
+				return err
+			}
+			linked = err == nil
+		}
+
+		if !linked {
+			if !config.OIDCCreateAdmins {
+				return errOIDCNoAccount
+			}
+			created, err := newOIDCAdmin(tx, claims, email, emailVerified)
+			if err != nil {
+				return err
+			}
+			admin = created
+		}
+
+		return tx.Create(&models.AdminIdentity{
+			AdminID:     admin.ID,
+			Issuer:      config.OIDCIssuer,
+			Subject:     subject,
+			Email:       email,
+			LastLoginAt: &now,
+			CreatedAt:   now,
+		}).Error
+	})
+	return admin, err
+}
+
+var usernameUnsafe = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+func newOIDCAdmin(tx *gorm.DB, claims map[string]interface{}, email string, emailVerified bool) (models.Admin, error) {
+	var admin models.Admin
+
+	base := utils.ClaimString(claims, config.OIDCUsernameClaim)
+	if base == "" && email != "" {
+		base, _, _ = strings.Cut(email, "@")
+	}
+	base = strings.Trim(usernameUnsafe.ReplaceAllString(base, "-"), "-")
+	if base == "" {
+		base = "user"
+	}
+
+	// Suffix the username until it is free
+	username := base
+	for i := 2; ; i++ {
+		var count int64
+		if err := tx.Model(&models.Admin{}).Where("username = ?", username).Count(&count).Error; err != nil {
+			return admin, err
+		}
+		if count == 0 {
+			break
+		}
+		username = fmt.Sprintf("%s-%d", base, i)
+	}
+
+	// SSO admins get a random password; they can set a real one through a reset
+	secret, err := utils.RandomToken()
+	if err != nil {
+		return admin, err
+	}
+	hashedPassword, err := utils.HashPassword(secret)
+	if err != nil {
+		return admin, err
+	}
+
+	var admins int64
+	if err := tx.Model(&models.Admin{}).Count(&admins).Error; err != nil {
+		return admin, err
+	}
+
+	now := time.Now()
+	admin = models.Admin{
+		Username:     username,
+		Password:     hashedPassword,
+		IsSuperadmin: admins == 0,
+		CreatedAt:    now,
+	}
+	if emailVerified {
+		var taken int64
+		if err := tx.Model(&models.Admin{}).Where("LOWER(email) = LOWER(?)", email).Count(&taken).Error; err != nil {
+			return admin, err
+		}
+		if taken == 0 {
+			admin.Email = &email
+			admin.EmailVerifiedAt = &now
+		}
+	}
+
+	if err := tx.Create(&admin).Error; err != nil {
+		return admin, err
+	}
+	log.Printf("Created admin %d (%s) on first OIDC sign-in", admin.ID, admin.Username)
+	return admin, nil
+}
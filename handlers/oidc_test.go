@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gofamtree/cmd/mockoidc/provider"
+	"gofamtree/config"
+	"gofamtree/models"
+	"gofamtree/utils"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const testRedirectURL = "http://gofamtree.test/admin/oidc/callback"
+
+// withMockOIDC points the SSO handlers at a mock provider served by httptest.
+// Admins are looked up by a stub that records the claims it was given, since
+// the tests run without a database.
+func withMockOIDC(t *testing.T) (*httptest.Server, *map[string]interface{}) {
+	t.Helper()
+
+	var p *provider.Provider
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.Handler().ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	var err error
+	p, err = provider.New(provider.Config{
+		Issuer:       server.URL,
+		ClientID:     "gofamtree",
+		ClientSecret: "secret",
+		Subject:      "mock-user-1",
+		Email:        "mock.user@example.com",
+		Username:     "mockuser",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	savedClient, savedIssuer, savedRedirect := utils.OIDC, config.OIDCIssuer, config.OIDCPostLoginRedirect
+	savedSecret, savedFind := config.JWTSecret, findOIDCAdmin
+	t.Cleanup(func() {
+		utils.OIDC, config.OIDCIssuer, config.OIDCPostLoginRedirect = savedClient, savedIssuer, savedRedirect
+		config.JWTSecret, findOIDCAdmin = savedSecret, savedFind
+	})
+
+	utils.OIDC = &utils.OIDCClient{
+		Issuer:       server.URL,
+		ClientID:     "gofamtree",
+		ClientSecret: "secret",
+		RedirectURL:  testRedirectURL,
+		Scopes:       []string{"openid", "profile", "email"},
+		HTTP:         server.Client(),
+	}
+	config.OIDCIssuer = server.URL
+	config.OIDCPostLoginRedirect = ""
+	config.JWTSecret = []byte(strings.Repeat("k", 32))
+
+	var claims map[string]interface{}
+	findOIDCAdmin = func(c map[string]interface{}) (models.Admin, error) {
+		claims = c
+		// 2FA keeps the response free of database-backed sessions
+		return models.Admin{ID: 42, Username: "mockuser", TOTPEnabled: true}, nil
+	}
+	return server, &claims
+}
+
+// startOIDCLogin runs OIDCLogin and follows the provider's authorization
+// redirect, returning the callback URL and the state cookie
+func startOIDCLogin(t *testing.T, server *httptest.Server) (*url.URL, *http.Cookie) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	OIDCLogin(rec, httptest.NewRequest("GET", "/admin/oidc/login", nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("login answered %d: %s", rec.Code, rec.Body)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != oidcStateCookie || !cookies[0].HttpOnly {
+		t.Fatalf("login set cookies %v, want one HttpOnly %s", cookies, oidcStateCookie)
+	}
+
+	client := server.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := client.Get(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("provider answered %d", resp.StatusCode)
+	}
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || !strings.HasPrefix(callback.String(), testRedirectURL) {
+		t.Fatalf("provider redirected to %q", resp.Header.Get("Location"))
+	}
+	return callback, cookies[0]
+}
+
+func callOIDCCallback(callback *url.URL, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", callback.RequestURI(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	OIDCCallback(rec, req)
+	return rec
+}
+
+func decodeError(t *testing.T, rec *httptest.ResponseRecorder) ErrorBody {
+	t.Helper()
+	var resp ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Error
+}
+
+func TestOIDCLoginAgainstMockProvider(t *testing.T) {
+	server, claims := withMockOIDC(t)
+	callback, cookie := startOIDCLogin(t, server)
+
+	rec := callOIDCCallback(callback, cookie)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("callback answered %d: %s", rec.Code, rec.Body)
+	}
+	var challenge MFAChallengeResponse
+	if err := json.NewDecoder(rec.Body).Decode(&challenge); err != nil {
+		t.Fatal(err)
+	}
+	if !challenge.MFARequired {
+		t.Fatal("callback did not ask for the second factor")
+	}
+	if parsed, err := utils.ParseMFAToken(challenge.MFAToken); err != nil || parsed.Subject != 42 {
+		t.Fatalf("MFA token = %+v, %v; want one for admin 42", parsed, err)
+	}
+
+	if got := utils.ClaimString(*claims, "sub"); got != "mock-user-1" {
+		t.Errorf("sub = %q, want mock-user-1", got)
+	}
+	if got := utils.ClaimString(*claims, "email"); got != "mock.user@example.com" || !utils.ClaimBool(*claims, "email_verified") {
+		t.Errorf("email = %q (verified %v), want a verified mock.user@example.com", got, (*claims)["email_verified"])
+	}
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == oidcStateCookie && c.MaxAge >= 0 {
+			t.Errorf("callback kept the state cookie: %v", c)
+		}
+	}
+}
+
+func TestOIDCCallbackRejectsForeignState(t *testing.T) {
+	server, claims := withMockOIDC(t)
+
+	tests := []struct {
+		name   string
+		cookie func(own *http.Cookie) *http.Cookie
+	}{
+		{"no cookie", func(*http.Cookie) *http.Cookie { return nil }},
+		{"other login's cookie", func(own *http.Cookie) *http.Cookie {
+			return &http.Cookie{Name: oidcStateCookie, Value: own.Value + "x"}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			callback, cookie := startOIDCLogin(t, server)
+			rec := callOIDCCallback(callback, tt.cookie(cookie))
+			if rec.Code != http.StatusBadRequest || decodeError(t, rec).Code != ErrCodeSSOStateInvalid {
+				t.Fatalf("callback answered %d: %s", rec.Code, rec.Body)
+			}
+			if *claims != nil {
+				t.Fatal("callback signed in with a state from another browser")
+			}
+		})
+	}
+}
+
+func TestOIDCCallbackStateIsSingleUse(t *testing.T) {
+	server, _ := withMockOIDC(t)
+	callback, cookie := startOIDCLogin(t, server)
+
+	if rec := callOIDCCallback(callback, cookie); rec.Code != http.StatusOK {
+		t.Fatalf("first callback answered %d: %s", rec.Code, rec.Body)
+	}
+	rec := callOIDCCallback(callback, cookie)
+	if rec.Code != http.StatusBadRequest || decodeError(t, rec).Code != ErrCodeSSOStateInvalid {
+		t.Fatalf("replayed callback answered %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestOIDCCallbackHidesProviderError(t *testing.T) {
+	withMockOIDC(t)
+
+	callback, _ := url.Parse(testRedirectURL + "?error=%3Cscript%3Ealert(1)%3C%2Fscript%3E&state=x")
+	rec := callOIDCCallback(callback, nil)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("callback answered %d: %s", rec.Code, rec.Body)
+	}
+	if body := decodeError(t, rec); body.Code != ErrCodeSSOFailed || body.Message != "Sign-in failed" {
+		t.Fatalf("error = %+v, want a generic sso_failed", body)
+	}
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"gofamtree/config"
+	"gofamtree/models"
+	"gofamtree/utils"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type ForgotPasswordInput struct {
+	Username string `json:"username"`
+}
+
+type ResetPasswordInput struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+func (input ChangePasswordInput) validate(v *validator) {
+	v.required("current_password", input.CurrentPassword)
+	v.maxLength("current_password", input.CurrentPassword, maxSecretLength)
+	v.required("new_password", input.NewPassword)
+}
+
+func (input ForgotPasswordInput) validate(v *validator) {
+	v.required("username", input.Username)
+	v.maxLength("username", input.Username, maxUsernameLength)
+}
+
+func (input ResetPasswordInput) validate(v *validator) {
+	v.required("token", input.Token)
+	v.maxLength("token", input.Token, maxSecretLength)
+	v.required("new_password", input.NewPassword)
+}
+
+func ChangePassword(w http.ResponseWriter, r *http.Request) {
+	if !requireInteractiveLogin(w, r) {
+		return
+	}
+
+	var input ChangePasswordInput
+	if !decodeInput(w, r, &input) {
+		return
+	}
+
+	var admin models.Admin
+	if err := config.DB.First(&admin, currentAdminID(r)).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodeAdminNotFound, "Admin not found")
+		return
+	}
+
+	if !utils.CheckPasswordHash(input.CurrentPassword, admin.Password) {
+		WriteError(w, r, http.StatusUnauthorized, ErrCodeInvalidCredentials, "Current password is incorrect")
+		return
+	}
+
+	if err := utils.CheckPasswordPolicy(input.NewPassword, admin.Username); err != nil {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeWeakPassword, err.Error())
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(input.NewPassword)
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to hash password")
+		return
+	}
+
+	// Sign out every other device, which may be where the old password leaked
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&admin).Update("password", hashedPassword).Error; err != nil {
+			return err
+		}
+		return revokeSessions(tx, admin.ID, utils.SessionIDFromContext(r.Context()))
+	})
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to change password")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Password changed successfully",
+	})
+}
+
+// ForgotPassword emails a single-use reset token. It answers the same way whether
+// or not the account exists, so it cannot be used to discover usernames.
+func ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var input ForgotPasswordInput
+	if !decodeInput(w, r, &input) {
+		return
+	}
+
+	var admin models.Admin
+	if err := config.DB.Where("username = ?", input.Username).First(&admin).Error; err == nil && admin.Email != nil {
+		if err := sendPasswordReset(admin); err != nil {
+			log.Printf("Failed to send password reset for admin %d: %v", admin.ID, err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "If the account exists and has an email address, a reset link has been sent",
+	})
+}
+
+func sendPasswordReset(admin models.Admin) error {
+	token, err := utils.RandomToken()
+	if err != nil {
+		return err
+	}
+
+	reset := models.PasswordReset{
+		AdminID:   admin.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(config.PasswordResetTTL),
+		CreatedAt: time.Now(),
+	}
+	if err := config.DB.Create(&reset).Error; err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Hello %s,\n\nSomeone asked to reset your GoFamTree password.\n", admin.Username)
+	if config.AppBaseURL != "" {
+		body += fmt.Sprintf("Open this link to choose a new password:\n\n%s/reset-password?token=%s\n",
+			config.AppBaseURL, url.QueryEscape(token))
+	} else {
+		body += fmt.Sprintf("Use this reset token to choose a new password:\n\n%s\n", token)
+	}
+	body += fmt.Sprintf("\nThe token expires in %s and can only be used once. If you did not ask for this, ignore this email.\n",
+		config.PasswordResetTTL)
+
+	return utils.Mailer.Send(utils.Mail{
+		To:      *admin.Email,
+		Subject: "Reset your GoFamTree password",
+		Body:    body,
+	})
+}
+
+func ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var input ResetPasswordInput
+	if !decodeInput(w, r, &input) {
+		return
+	}
+
+	var reset models.PasswordReset
+	if err := config.DB.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?",
+		utils.HashToken(input.Token), time.Now()).First(&reset).Error; err != nil {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeResetTokenInvalid, "Invalid or expired reset token")
+		return
+	}
+
+	var admin models.Admin
+	if err := config.DB.Select("id", "username").First(&admin, reset.AdminID).Error; err != nil {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeResetTokenInvalid, "Invalid or expired reset token")
+		return
+	}
+	if err := utils.CheckPasswordPolicy(input.NewPassword, admin.Username); err != nil {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeWeakPassword, err.Error())
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(input.NewPassword)
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to hash password")
+		return
+	}
+
+	// Set the password, burn every outstanding reset token and sign out every session
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.PasswordReset{}).
+			Where("id = ? AND used_at IS NULL", reset.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Model(&models.PasswordReset{}).
+			Where("admin_id = ? AND used_at IS NULL", reset.AdminID).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Admin{}).Where("id = ?", reset.AdminID).Update("password", hashedPassword).Error; err != nil {
+			return err
+		}
+		// The reset token was mailed to the admin, so their address is verified
+		if err := tx.Model(&models.Admin{}).Where("id = ? AND email IS NOT NULL AND email_verified_at IS NULL", reset.AdminID).
+			Update("email_verified_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return revokeSessions(tx, reset.AdminID, 0)
+	})
+	if err == gorm.ErrRecordNotFound {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeResetTokenInvalid, "Invalid or expired reset token")
+		return
+	}
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to reset password")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Password reset successfully",
+	})
+}
@@ -2,74 +2,134 @@ package handlers
 
 import (
 	"encoding/json"
-	"fmt"
 	"gofamtree/config"
 	"gofamtree/models"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type CreatePersonInput struct {
-	HouseID     uint   `json:"house_id"`
-	Name        string `json:"name"`
-	Contact     string `json:"contact"`
-	Description string `json:"description"`
-	Gender      string `json:"gender"` // male/female
-	DOB         string `json:"dob"`    // in format YYYY-MM-DD (optional)
+	HouseID uint `json:"house_id"`
+	personFields
 }
 
 type UpdatePersonInput struct {
-	Name        string `json:"name"`
-	Contact     string `json:"contact"`
-	Description string `json:"description"`
-	Gender      string `json:"gender"`
-	DOB         string `json:"dob"`
+	personFields
+}
+
+// personFields are the person attributes shared by create and update
+type personFields struct {
+	Name         string `json:"name"`
+	Contact      string `json:"contact"`
+	Description  string `json:"description"`
+	Gender       string `json:"gender"`         // one of GENDERS, unknown when omitted
+	DOB          string `json:"dob"`            // e.g. 1925-03-15, March 1925, about 1890 (optional)
+	DOD          string `json:"dod"`            // date of death, same formats as dob (optional)
+	BirthPlaceID *uint  `json:"birth_place_id"` // optional
+	DeathPlaceID *uint  `json:"death_place_id"` // optional
+	IsLiving     *bool  `json:"is_living"`      // omit to decide from dates
+	CauseOfDeath string `json:"cause_of_death"` // optional
+}
+
+func (input CreatePersonInput) validate(v *validator) {
+	v.requiredID("house_id", input.HouseID)
+	input.personFields.validate(v)
+}
+
+func (input personFields) validate(v *validator) {
+	v.required("name", input.Name)
+	v.maxLength("name", input.Name, maxNameLength)
+	v.maxLength("contact", input.Contact, maxContactLength)
+	v.maxLength("description", input.Description, maxDescriptionLength)
+	v.oneOf("gender", input.Gender, config.Genders...)
+	v.genDate("dob", input.DOB)
+	v.genDate("dod", input.DOD)
+	v.maxLength("cause_of_death", input.CauseOfDeath, maxContactLength)
+
+	dob, dobErr := models.ParseGenDate(input.DOB)
+	dod, dodErr := models.ParseGenDate(input.DOD)
+	if dobErr == nil && dodErr == nil && dod.EndsBefore(dob) {
+		v.add("dod", "must not be before dob")
+	}
+	if dodErr == nil && dod.SortDate().After(time.Now()) {
+		v.add("dod", "must not be in the future")
+	}
+	if input.IsLiving != nil && *input.IsLiving && (input.DOD != "" || input.CauseOfDeath != "" || input.DeathPlaceID != nil) {
+		v.add("is_living", "must be false or omitted for a person with dod, death_place_id or cause_of_death")
+	}
+	if input.BirthPlaceID != nil && *input.BirthPlaceID == 0 {
+		v.add("birth_place_id", "must be a place ID or null")
+	}
+	if input.DeathPlaceID != nil && *input.DeathPlaceID == 0 {
+		v.add("death_place_id", "must be a place ID or null")
+	}
+}
+
+// personGender records a gender left out as unknown
+func personGender(gender string) string {
+	if gender == "" {
+		return config.GenderUnknown
+	}
+	return gender
+}
+
+// parseOptionalDate converts a validated genealogical date, nil when empty
+func parseOptionalDate(value string) *models.GenDate {
+	if value == "" {
+		return nil
+	}
+	parsed, _ := models.ParseGenDate(value)
+	return &parsed
 }
 
 func CreatePerson(w http.ResponseWriter, r *http.Request) {
 	var input CreatePersonInput
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
+	if !decodeInput(w, r, &input) {
 		return
 	}
 
-	// Validate that house exists
-	var house models.House
-	if err := config.DB.First(&house, input.HouseID).Error; err != nil {
-		http.Error(w, "House not found", http.StatusBadRequest)
+	// Validate that house exists and the caller may edit it
+	role := houseRole(r, input.HouseID)
+	if role == "" {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeHouseNotFound, "House not found")
 		return
 	}
-	
-
-
-	person := models.Person{
-		HouseID:     input.HouseID,
-		Name:        input.Name,
-		Contact:     input.Contact,
-		Description: input.Description,
-		Gender:      input.Gender,
-		CreatedAt:   time.Now(),
+	if !hasRole(role, models.RoleEditor) {
+		WriteError(w, r, http.StatusForbidden, ErrCodeInsufficientRole, "Requires editor role on this house")
+		return
+	}
+	if !checkPlace(w, r, input.HouseID, input.BirthPlaceID) || !checkPlace(w, r, input.HouseID, input.DeathPlaceID) {
+		return
 	}
 
-	// Parse DOB if provided
-	if input.DOB != "" {
-		parsedDOB, err := time.Parse("2006-01-02", input.DOB)
-		if err != nil {
-			http.Error(w, "Invalid date format. Use YYYY-MM-DD", http.StatusBadRequest)
-			return
-		}
-		person.DOB = &parsedDOB
+	person := models.Person{
+		HouseID:      input.HouseID,
+		Name:         input.Name,
+		Contact:      input.Contact,
+		Description:  input.Description,
+		Gender:       personGender(input.Gender),
+		DOB:          parseOptionalDate(input.DOB),
+		DOD:          parseOptionalDate(input.DOD),
+		BirthPlaceID: input.BirthPlaceID,
+		DeathPlaceID: input.DeathPlaceID,
+		IsLiving:     input.IsLiving,
+		CauseOfDeath: input.CauseOfDeath,
+		CreatedAt:    time.Now(),
 	}
 
 	if err := config.DB.Create(&person).Error; err != nil {
-		http.Error(w, "Failed to create person", http.StatusInternalServerError)
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to create person")
 		return
 	}
+	recordAudit(r, person.HouseID, models.AuditCreate, "person", person.ID, nil, person)
 
 	// Load relationships
-	config.DB.Preload("House").First(&person, person.ID)
+	config.DB.Preload("House").Preload("BirthPlace").Preload("DeathPlace").First(&person, person.ID)
+	newPrivacyFilter(r).person(&person)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -78,27 +138,79 @@ func CreatePerson(w http.ResponseWriter, r *http.Request) {
 
 func GetPersons(w http.ResponseWriter, r *http.Request) {
 	var persons []models.Person
-	// Validate request
+	// Only persons in houses the caller can reach
+	query := config.DB.Where("house_id IN (?)", accessibleHouseIDs(r, models.RoleViewer))
 	
 	// Optional: filter by house_id if provided as query parameter
-	houseID := r.URL.Query().Get("house_id")
-	
-	if houseID != "" {
-		if err := config.DB.Where("house_id = ?", houseID).Preload("House").Find(&persons).Error; err != nil {
-			http.Error(w, "Failed to fetch persons", http.StatusInternalServerError)
+	if value := r.URL.Query().Get("house_id"); value != "" {
+		houseID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidQuery, "Invalid house_id")
 			return
 		}
-	} else {
-		if err := config.DB.Preload("House").Find(&persons).Error; err != nil {
-			http.Error(w, "Failed to fetch persons", http.StatusInternalServerError)
+		query = query.Where("house_id = ?", uint(houseID))
+	}
+
+	// Optional: living or deceased only, as the privacy rules decide it
+	switch r.URL.Query().Get("status") {
+	case "":
+	case models.StatusLiving:
+		query = query.Where(models.PresumedLivingSQL)
+	case models.StatusDeceased:
+		query = query.Where("NOT " + models.PresumedLivingSQL)
+	default:
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidQuery, "Invalid status. Use living or deceased")
+		return
+	}
+
+	// Optional: search all names of the persons, not just the preferred one
+	if q := strings.TrimSpace(r.URL.Query().Get("q")); q != "" {
+		pattern := "%" + escapeLike(q) + "%"
+		query = query.Where("name ILIKE ? OR id IN (?)", pattern,
+			config.DB.Model(&models.PersonName{}).Select("person_id").
+				Where(models.PersonNameSQL+" ILIKE ? OR "+models.PersonNameReversedSQL+" ILIKE ?", pattern, pattern))
+	}
+
+	// Optional: born, died or with an event in a place or anywhere within it
+	placeFilter := r.URL.Query().Get("place_id")
+	if placeFilter != "" {
+		placeID, err := strconv.ParseUint(placeFilter, 10, 32)
+		if err != nil {
+			WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidQuery, "Invalid place_id")
 			return
 		}
+		places := placeSubtree(uint(placeID))
+		query = query.Where("birth_place_id IN (?) OR death_place_id IN (?) OR id IN (?)", places, places,
+			config.DB.Model(&models.EventParticipant{}).Select("person_id").
+				Where("event_id IN (?)", config.DB.Model(&models.Event{}).Select("id").Where("place_id IN (?)", places)))
+	}
+
+	if err := query.Preload("House").Preload("BirthPlace").Preload("DeathPlace").Preload("Names", preferredFirst).Find(&persons).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to fetch persons")
+		return
+	}
+	newPrivacyFilter(r).persons(persons)
+
+	// Places of withheld persons must not show through the filter either
+	if placeFilter != "" {
+		persons = withoutRedacted(persons)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(persons)
 }
 
+// withoutRedacted drops the persons whose private fields were withheld
+func withoutRedacted(persons []models.Person) []models.Person {
+	visible := persons[:0]
+	for _, person := range persons {
+		if !person.Redacted {
+			visible = append(visible, person)
+		}
+	}
+	return visible
+}
+
 func GetPerson(w http.ResponseWriter, r *http.Request) {
 	
 	
@@ -106,31 +218,26 @@ func GetPerson(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/persons/")
 	id, err := strconv.ParseUint(path, 10, 32)
 	if err != nil {
-		http.Error(w, "Invalid person ID", http.StatusBadRequest)
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid person ID")
 		return
 	}
 	
 	var person models.Person
-	
-	// Debug: Add logging to see what GORM is doing
-	fmt.Printf("Looking for person with ID: %d\n", uint(id))
-	
-	// Debug: Check what table name GORM is using
-	tableName := config.DB.NamingStrategy.TableName("Person")
-	fmt.Printf("GORM is looking in table: %s\n", tableName)
-	
-	// Debug: Try a raw SQL query first to verify data exists
-	var count int64
-	config.DB.Table("persons").Where("id = ?", uint(id)).Count(&count)
-	fmt.Printf("Raw SQL count for id %d: %d\n", uint(id), count)
-	
-	if err := config.DB.Preload("House").First(&person, uint(id)).Error; err != nil {
-		fmt.Printf("GORM Error: %v\n", err)
-		http.Error(w, "Person not found", http.StatusNotFound)
+	if err := config.DB.Where("house_id IN (?)", accessibleHouseIDs(r, models.RoleViewer)).
+		Preload("House").Preload("BirthPlace").Preload("DeathPlace").Preload("Names", preferredFirst).First(&person, uint(id)).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodePersonNotFound, "Person not found")
 		return
 	}
-	
-	fmt.Printf("Found person: %+v\n", person)
+
+	events, err := personEvents(person.ID)
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to fetch events")
+		return
+	}
+	privacy := newPrivacyFilter(r)
+	privacy.person(&person)
+	privacy.events(events)
+	person.Events = events
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(person)
@@ -141,72 +248,137 @@ func UpdatePerson(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/persons/")
 	id, err := strconv.ParseUint(path, 10, 32)
 	if err != nil {
-		http.Error(w, "Invalid person ID", http.StatusBadRequest)
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid person ID")
 		return
 	}
 
 	var input UpdatePersonInput
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
+	if !decodeInput(w, r, &input) {
 		return
 	}
 
 	var person models.Person
-	if err := config.DB.First(&person, uint(id)).Error; err != nil {
-		http.Error(w, "Person not found", http.StatusNotFound)
+	if err := config.DB.Where("house_id IN (?)", accessibleHouseIDs(r, models.RoleViewer)).First(&person, uint(id)).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodePersonNotFound, "Person not found")
 		return
 	}
 
-	// Update fields
-	person.Name = input.Name
-	person.Contact = input.Contact
-	person.Description = input.Description
-	person.Gender = input.Gender
+	if !requireHouseRole(w, r, person.HouseID, models.RoleEditor) {
+		return
+	}
+	if !checkPlace(w, r, person.HouseID, input.BirthPlaceID) || !checkPlace(w, r, person.HouseID, input.DeathPlaceID) {
+		return
+	}
 
-	// Parse DOB if provided
-	if input.DOB != "" {
-		parsedDOB, err := time.Parse("2006-01-02", input.DOB)
-		if err != nil {
-			http.Error(w, "Invalid date format. Use YYYY-MM-DD", http.StatusBadRequest)
-			return
-		}
-		person.DOB = &parsedDOB
-	} else {
-		person.DOB = nil
+	// Callers who only see this person redacted cannot clear what they were never shown
+	privacy := newPrivacyFilter(r)
+	keepPrivate := !privacy.canSeePrivate(&person)
+
+	before := person
+	applyPersonUpdate(&person, input.personFields, keepPrivate)
+
+	// Nor may they reveal the person by changing the dates or living status
+	if keepPrivate && privacy.canSeePrivate(&person) {
+		WriteError(w, r, http.StatusForbidden, ErrCodeInsufficientRole, "Requires the house's privacy role to mark a living person as deceased")
+		return
 	}
 
 	if err := config.DB.Save(&person).Error; err != nil {
-		http.Error(w, "Failed to update person", http.StatusInternalServerError)
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to update person")
 		return
 	}
+	recordAudit(r, person.HouseID, models.AuditUpdate, "person", person.ID, before, person)
 
 	// Load relationships
-	config.DB.Preload("House").First(&person, person.ID)
+	config.DB.Preload("House").Preload("BirthPlace").Preload("DeathPlace").First(&person, person.ID)
+	privacy.person(&person)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(person)
 }
 
+// applyPersonUpdate copies an update onto a person. With keepPrivate, private
+// fields left empty keep their stored values.
+func applyPersonUpdate(person *models.Person, input personFields, keepPrivate bool) {
+	person.Name = input.Name
+	person.Gender = personGender(input.Gender)
+	person.IsLiving = input.IsLiving
+	if !keepPrivate || input.Contact != "" {
+		person.Contact = input.Contact
+	}
+	if !keepPrivate || input.Description != "" {
+		person.Description = input.Description
+	}
+	if !keepPrivate || input.CauseOfDeath != "" {
+		person.CauseOfDeath = input.CauseOfDeath
+	}
+	if !keepPrivate || input.DOB != "" {
+		person.DOB = parseOptionalDate(input.DOB)
+	}
+	if !keepPrivate || input.DOD != "" {
+		person.DOD = parseOptionalDate(input.DOD)
+	}
+	if !keepPrivate || input.BirthPlaceID != nil {
+		person.BirthPlaceID = input.BirthPlaceID
+	}
+	if !keepPrivate || input.DeathPlaceID != nil {
+		person.DeathPlaceID = input.DeathPlaceID
+	}
+}
+
 func DeletePerson(w http.ResponseWriter, r *http.Request) {
 	// Extract ID from URL path
 	path := strings.TrimPrefix(r.URL.Path, "/persons/")
 	id, err := strconv.ParseUint(path, 10, 32)
 	if err != nil {
-		http.Error(w, "Invalid person ID", http.StatusBadRequest)
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid person ID")
 		return
 	}
 
 	
 	var person models.Person
-	if err := config.DB.First(&person, uint(id)).Error; err != nil {
-		http.Error(w, "Person not found", http.StatusNotFound)
+	if err := config.DB.Where("house_id IN (?)", accessibleHouseIDs(r, models.RoleViewer)).First(&person, uint(id)).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodePersonNotFound, "Person not found")
 		return
 	}
 
-	// Delete all relations involving this person
-	config.DB.Where("person_id = ? OR related_to_id = ?", uint(id), uint(id)).Delete(&models.Relation{})
-	// Delete the person
-	config.DB.Delete(&person)
+	if !requireHouseRole(w, r, person.HouseID, models.RoleEditor) {
+		return
+	}
+
+	// Delete the person with their relations, events and names, or nothing if any step fails
+	var relations []models.Relation
+	var events []models.Event
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Delete all relations involving this person
+		if err := tx.Where("person_id = ? OR related_to_id = ?", person.ID, person.ID).Find(&relations).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("person_id = ? OR related_to_id = ?", person.ID, person.ID).Delete(&models.Relation{}).Error; err != nil {
+			return err
+		}
+		// Remove the person from their events
+		var err error
+		if events, err = deletePersonEvents(tx, person.ID); err != nil {
+			return err
+		}
+		// Delete the person's other names
+		if err := tx.Where("person_id = ?", person.ID).Delete(&models.PersonName{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&person).Error
+	})
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to delete person")
+		return
+	}
+	for _, relation := range relations {
+		recordAudit(r, relation.HouseID, models.AuditDelete, "relation", relation.ID, relation, nil)
+	}
+	for _, event := range events {
+		recordAudit(r, event.HouseID, models.AuditDelete, "event", event.ID, event, nil)
+	}
+	recordAudit(r, person.HouseID, models.AuditDelete, "person", person.ID, person, nil)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
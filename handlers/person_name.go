@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gofamtree/config"
+	"gofamtree/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PersonNameInput struct {
+	NameType  string `json:"name_type"` // birth/married/nickname/alias
+	Prefix    string `json:"prefix"`    // optional, e.g. Dr.
+	GivenName string `json:"given_name"`
+	Surname   string `json:"surname"`
+	Suffix    string `json:"suffix"`    // optional, e.g. Jr.
+	Language  string `json:"language"`  // language or script (optional)
+	Preferred bool   `json:"preferred"` // becomes the person's name
+}
+
+func (input PersonNameInput) validate(v *validator) {
+	v.required("name_type", input.NameType)
+	v.oneOf("name_type", input.NameType, models.NameTypes...)
+	if strings.TrimSpace(input.GivenName) == "" && strings.TrimSpace(input.Surname) == "" {
+		v.add("given_name", "is required without surname")
+	}
+	v.maxLength("prefix", input.Prefix, maxLabelLength)
+	v.maxLength("given_name", input.GivenName, maxNameLength)
+	v.maxLength("surname", input.Surname, maxNameLength)
+	v.maxLength("suffix", input.Suffix, maxLabelLength)
+	v.maxLength("language", input.Language, maxLabelLength)
+	if input.Preferred {
+		v.maxLength("preferred", input.name().FullName(), maxNameLength)
+	}
+}
+
+// name converts a validated input to a row
+func (input PersonNameInput) name() models.PersonName {
+	return models.PersonName{
+		NameType:  input.NameType,
+		Prefix:    input.Prefix,
+		GivenName: input.GivenName,
+		Surname:   input.Surname,
+		Suffix:    input.Suffix,
+		Language:  input.Language,
+		Preferred: input.Preferred,
+	}
+}
+
+// parsePersonNamePath extracts the IDs from /persons/{id}/names[/{nameId}];
+// nameID is 0 for the collection
+func parsePersonNamePath(w http.ResponseWriter, r *http.Request) (personID, nameID uint, ok bool) {
+	personPart, namePart, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/persons/"), "/names")
+	id, err := strconv.ParseUint(personPart, 10, 32)
+	if err != nil {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid person ID")
+		return 0, 0, false
+	}
+	namePart = strings.TrimPrefix(namePart, "/")
+	if namePart == "" {
+		return uint(id), 0, true
+	}
+	nid, err := strconv.ParseUint(namePart, 10, 32)
+	if err != nil {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid name ID")
+		return 0, 0, false
+	}
+	return uint(id), uint(nid), true
+}
+
+// namedPerson loads the person of a names request, checking the caller's role
+// on their house
+func namedPerson(w http.ResponseWriter, r *http.Request, personID uint, role string) (models.Person, bool) {
+	var person models.Person
+	if err := config.DB.Where("house_id IN (?)", accessibleHouseIDs(r, models.RoleViewer)).First(&person, personID).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodePersonNotFound, "Person not found")
+		return person, false
+	}
+	if !requireHouseRole(w, r, person.HouseID, role) {
+		return person, false
+	}
+	return person, true
+}
+
+// preferredFirst orders a person's names with the preferred one first
+func preferredFirst(db *gorm.DB) *gorm.DB {
+	return db.Order("preferred DESC, id")
+}
+
+// savePersonName stores a name. A preferred name replaces any other preferred
+// name of the person and becomes their name.
+func savePersonName(r *http.Request, person *models.Person, name *models.PersonName) error {
+	before := *person
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Only one name may be preferred, so clear the others first
+		if name.Preferred {
+			if err := tx.Model(&models.PersonName{}).Where("person_id = ? AND id <> ?", person.ID, name.ID).
+				UpdateColumn("preferred", false).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Save(name).Error; err != nil {
+			return err
+		}
+		if !name.Preferred {
+			return nil
+		}
+		if person.Name == name.FullName() {
+			return nil
+		}
+		person.Name = name.FullName()
+		return tx.Model(person).UpdateColumn("name", person.Name).Error
+	})
+	if err == nil && person.Name != before.Name {
+		recordAudit(r, person.HouseID, models.AuditUpdate, "person", person.ID, before, *person)
+	}
+	return err
+}
+
+func GetPersonNames(w http.ResponseWriter, r *http.Request) {
+	personID, _, ok := parsePersonNamePath(w, r)
+	if !ok {
+		return
+	}
+	person, ok := namedPerson(w, r, personID, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	var names []models.PersonName
+	if err := preferredFirst(config.DB.Where("person_id = ?", person.ID)).Find(&names).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to fetch names")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(names)
+}
+
+func CreatePersonName(w http.ResponseWriter, r *http.Request) {
+	personID, _, ok := parsePersonNamePath(w, r)
+	if !ok {
+		return
+	}
+
+	var input PersonNameInput
+	if !decodeInput(w, r, &input) {
+		return
+	}
+
+	person, ok := namedPerson(w, r, personID, models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	name := input.name()
+	name.PersonID = person.ID
+	name.CreatedAt = time.Now()
+	if err := savePersonName(r, &person, &name); err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to create name")
+		return
+	}
+	recordAudit(r, person.HouseID, models.AuditCreate, "person_name", name.ID, nil, name)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(name)
+}
+
+func UpdatePersonName(w http.ResponseWriter, r *http.Request) {
+	personID, nameID, ok := parsePersonNamePath(w, r)
+	if !ok {
+		return
+	}
+
+	var input PersonNameInput
+	if !decodeInput(w, r, &input) {
+		return
+	}
+
+	person, ok := namedPerson(w, r, personID, models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	var name models.PersonName
+	if err := config.DB.Where("person_id = ?", person.ID).First(&name, nameID).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodePersonNameNotFound, "Name not found")
+		return
+	}
+
+	before := name
+	updated := input.name()
+	updated.ID, updated.PersonID, updated.CreatedAt = name.ID, name.PersonID, name.CreatedAt
+	if err := savePersonName(r, &person, &updated); err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to update name")
+		return
+	}
+	recordAudit(r, person.HouseID, models.AuditUpdate, "person_name", updated.ID, before, updated)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func DeletePersonName(w http.ResponseWriter, r *http.Request) {
+	personID, nameID, ok := parsePersonNamePath(w, r)
+	if !ok {
+		return
+	}
+
+	person, ok := namedPerson(w, r, personID, models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	var name models.PersonName
+	if err := config.DB.Where("person_id = ?", person.ID).First(&name, nameID).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodePersonNameNotFound, "Name not found")
+		return
+	}
+
+	// The person keeps their name when the preferred one is deleted
+	if err := config.DB.Delete(&name).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to delete name")
+		return
+	}
+	recordAudit(r, person.HouseID, models.AuditDelete, "person_name", name.ID, name, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Name deleted successfully",
+	})
+}
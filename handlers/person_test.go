@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"gofamtree/config"
+	"gofamtree/models"
+	"testing"
+	"time"
+)
+
+// TestUpdatePersonCannotRevealLivingPerson covers callers below a house's
+// privacy_min_role: they may edit a living person they see redacted, but no
+// update may turn that person into one they see in full.
+func TestUpdatePersonCannotRevealLivingPerson(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	privacy := &privacyFilter{now: now, policies: map[uint]housePrivacy{
+		1: {fullAccess: false, livingYears: models.DefaultPrivacyLivingYears},
+	}}
+	no, yes := false, true
+
+	tests := []struct {
+		name    string
+		input   personFields
+		reveals bool
+	}{
+		{"rename", personFields{Name: "Jane Doe"}, false},
+		{"recent dob", personFields{Name: "Jane Doe", DOB: "1990"}, false},
+		{"explicitly living", personFields{Name: "Jane Doe", IsLiving: &yes}, false},
+		{"is_living false", personFields{Name: "Jane Doe", IsLiving: &no}, true},
+		{"old dob", personFields{Name: "Jane Doe", DOB: "1850-02-01"}, true},
+		{"dod", personFields{Name: "Jane Doe", DOD: "2020"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dob, _ := models.ParseGenDate("1990-05-04")
+			person := models.Person{ID: 7, HouseID: 1, Name: "Jane", DOB: &dob, Contact: "jane@example.com"}
+			keepPrivate := !privacy.canSeePrivate(&person)
+			if !keepPrivate {
+				t.Fatal("a living person is visible below the privacy role")
+			}
+
+			applyPersonUpdate(&person, tt.input, keepPrivate)
+			if got := privacy.canSeePrivate(&person); got != tt.reveals {
+				t.Errorf("update reveals the person = %v, want %v", got, tt.reveals)
+			}
+			if person.Contact != "jane@example.com" {
+				t.Errorf("contact = %q, want the stored value kept", person.Contact)
+			}
+			if tt.input.DOB == "" && person.DOB == nil {
+				t.Error("the stored dob was cleared")
+			}
+		})
+	}
+}
+
+func TestApplyPersonUpdateWithFullAccess(t *testing.T) {
+	dob, _ := models.ParseGenDate("1990-05-04")
+	person := models.Person{ID: 7, HouseID: 1, Name: "Jane", DOB: &dob, Contact: "jane@example.com"}
+
+	applyPersonUpdate(&person, personFields{Name: "Jane Doe"}, false)
+	if person.Contact != "" || person.DOB != nil {
+		t.Errorf("fields left empty were kept: contact %q, dob %v", person.Contact, person.DOB)
+	}
+	if person.Gender != config.GenderUnknown {
+		t.Errorf("gender = %q, want %q", person.Gender, config.GenderUnknown)
+	}
+}
@@ -0,0 +1,580 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gofamtree/config"
+	"gofamtree/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type CreatePlaceInput struct {
+	HouseID uint `json:"house_id"`
+	placeFields
+}
+
+type UpdatePlaceInput struct {
+	placeFields
+}
+
+// placeFields are the place attributes shared by create and update
+type placeFields struct {
+	Name      string           `json:"name"`
+	PlaceType string           `json:"place_type"` // village/district/province/country
+	ParentID  *uint            `json:"parent_id"`  // enclosing place (optional)
+	Latitude  *float64         `json:"latitude"`   // optional, with longitude
+	Longitude *float64         `json:"longitude"`  // optional, with latitude
+	Names     []PlaceNameInput `json:"names"`      // alternate and historical names
+}
+
+type PlaceNameInput struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`     // alternate/historical
+	Language string `json:"language"` // optional
+	From     string `json:"from"`     // genealogical date (optional)
+	To       string `json:"to"`       // genealogical date (optional)
+}
+
+type MergePlaceInput struct {
+	DuplicateID uint `json:"duplicate_id"` // merged into the place in the URL, then deleted
+}
+
+func (input CreatePlaceInput) validate(v *validator) {
+	v.requiredID("house_id", input.HouseID)
+	input.placeFields.validate(v)
+}
+
+func (input placeFields) validate(v *validator) {
+	v.required("name", input.Name)
+	v.maxLength("name", input.Name, maxNameLength)
+	v.required("place_type", input.PlaceType)
+	v.oneOf("place_type", input.PlaceType, models.PlaceTypes...)
+	if input.ParentID != nil && *input.ParentID == 0 {
+		v.add("parent_id", "must be a place ID or null")
+	}
+
+	if (input.Latitude == nil) != (input.Longitude == nil) {
+		v.add("latitude", "must be given together with longitude")
+	}
+	if input.Latitude != nil && (*input.Latitude < -90 || *input.Latitude > 90) {
+		v.add("latitude", "must be between -90 and 90")
+	}
+	if input.Longitude != nil && (*input.Longitude < -180 || *input.Longitude > 180) {
+		v.add("longitude", "must be between -180 and 180")
+	}
+
+	for i, name := range input.Names {
+		field := "names[" + strconv.Itoa(i) + "]"
+		v.required(field+".name", name.Name)
+		v.maxLength(field+".name", name.Name, maxNameLength)
+		v.required(field+".kind", name.Kind)
+		v.oneOf(field+".kind", name.Kind, models.PlaceNameAlternate, models.PlaceNameHistorical)
+		v.maxLength(field+".language", name.Language, maxLabelLength)
+		v.genDate(field+".from", name.From)
+		v.genDate(field+".to", name.To)
+
+		from, fromErr := models.ParseGenDate(name.From)
+		to, toErr := models.ParseGenDate(name.To)
+		if fromErr == nil && toErr == nil && to.EndsBefore(from) {
+			v.add(field+".to", "must not be before from")
+		}
+	}
+}
+
+func (input MergePlaceInput) validate(v *validator) {
+	v.requiredID("duplicate_id", input.DuplicateID)
+}
+
+// placeNames converts validated name inputs to rows
+func placeNames(inputs []PlaceNameInput) []models.PlaceName {
+	names := make([]models.PlaceName, 0, len(inputs))
+	for _, input := range inputs {
+		names = append(names, models.PlaceName{
+			Name:     input.Name,
+			Kind:     input.Kind,
+			Language: input.Language,
+			From:     parseOptionalDate(input.From),
+			To:       parseOptionalDate(input.To),
+		})
+	}
+	return names
+}
+
+// parsePlacePath extracts the ID from /places/{id}[/merge]
+func parsePlacePath(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/places/"), "/merge")
+	id, err := strconv.ParseUint(path, 10, 32)
+	if err != nil {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid place ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// placeSubtree is a subquery of the IDs of a place and every place within it
+func placeSubtree(id uint) *gorm.DB {
+	return config.DB.Raw(`WITH RECURSIVE subtree AS (
+		SELECT id FROM places WHERE id = ?
+		UNION
+		SELECT places.id FROM places JOIN subtree ON places.parent_id = subtree.id
+	) SELECT id FROM subtree`, id)
+}
+
+// withinPlace reports whether a place is another one or lies within it
+func withinPlace(id, ancestorID uint) bool {
+	var count int64
+	config.DB.Model(&models.Place{}).Where("id = ? AND id IN (?)", id, placeSubtree(ancestorID)).Count(&count)
+	return count > 0
+}
+
+// fillPlacePaths sets the path of each place from the names of its enclosing places
+func fillPlacePaths(places []models.Place) error {
+	if len(places) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(places))
+	for _, place := range places {
+		ids = append(ids, place.ID)
+	}
+
+	var ancestors []models.Place
+	if err := config.DB.Raw(`WITH RECURSIVE ancestors AS (
+		SELECT id, name, parent_id FROM places WHERE id IN ?
+		UNION
+		SELECT places.id, places.name, places.parent_id FROM places JOIN ancestors ON places.id = ancestors.parent_id
+	) SELECT id, name, parent_id FROM ancestors`, ids).Scan(&ancestors).Error; err != nil {
+		return err
+	}
+	byID := make(map[uint]models.Place, len(ancestors))
+	for _, ancestor := range ancestors {
+		byID[ancestor.ID] = ancestor
+	}
+
+	for i := range places {
+		names := []string{places[i].Name}
+		parentID := places[i].ParentID
+		// Bounded by the number of places, should the hierarchy ever hold a loop
+		for steps := 0; parentID != nil && steps < len(byID); steps++ {
+			parent, ok := byID[*parentID]
+			if !ok {
+				break
+			}
+			names = append(names, parent.Name)
+			parentID = parent.ParentID
+		}
+		places[i].Path = strings.Join(names, ", ")
+	}
+	return nil
+}
+
+// checkPlace verifies that an optional place reference belongs to a house
+func checkPlace(w http.ResponseWriter, r *http.Request, houseID uint, placeID *uint) bool {
+	if placeID == nil {
+		return true
+	}
+	var place models.Place
+	if err := config.DB.Select("id", "house_id").First(&place, *placeID).Error; err != nil {
+		WriteError(w, r, http.StatusBadRequest, ErrCodePlaceNotFound, "Place not found")
+		return false
+	}
+	if place.HouseID != houseID {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeHouseMismatch, "The place must belong to the same house")
+		return false
+	}
+	return true
+}
+
+// checkPlaceParent verifies that a place may sit within parentID. placeID is 0
+// for a new place.
+func checkPlaceParent(w http.ResponseWriter, r *http.Request, houseID, placeID uint, placeType string, parentID *uint) bool {
+	if parentID == nil {
+		return true
+	}
+	var parent models.Place
+	if err := config.DB.Select("id", "house_id", "place_type").First(&parent, *parentID).Error; err != nil {
+		WriteError(w, r, http.StatusBadRequest, ErrCodePlaceNotFound, "Parent place not found")
+		return false
+	}
+	if parent.HouseID != houseID {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeHouseMismatch, "The parent place must belong to the same house")
+		return false
+	}
+
+	var message string
+	if !models.PlaceContains(parent.PlaceType, placeType) {
+		message = "must be a larger kind of place than " + placeType
+	} else if placeID != 0 && withinPlace(parent.ID, placeID) {
+		message = "must not be the place itself or lie within it"
+	}
+	if message != "" {
+		WriteErrorDetails(w, r, http.StatusUnprocessableEntity, ErrCodeValidationFailed, "Validation failed",
+			[]FieldError{{Field: "parent_id", Message: message}})
+		return false
+	}
+	return true
+}
+
+func CreatePlace(w http.ResponseWriter, r *http.Request) {
+	var input CreatePlaceInput
+	if !decodeInput(w, r, &input) {
+		return
+	}
+
+	// Validate that house exists and the caller may edit it
+	role := houseRole(r, input.HouseID)
+	if role == "" {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeHouseNotFound, "House not found")
+		return
+	}
+	if !hasRole(role, models.RoleEditor) {
+		WriteError(w, r, http.StatusForbidden, ErrCodeInsufficientRole, "Requires editor role on this house")
+		return
+	}
+
+	if !checkPlaceParent(w, r, input.HouseID, 0, input.PlaceType, input.ParentID) {
+		return
+	}
+
+	place := models.Place{
+		HouseID:   input.HouseID,
+		Name:      input.Name,
+		PlaceType: input.PlaceType,
+		ParentID:  input.ParentID,
+		Latitude:  input.Latitude,
+		Longitude: input.Longitude,
+		CreatedAt: time.Now(),
+		Names:     placeNames(input.Names),
+	}
+
+	if err := config.DB.Create(&place).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to create place")
+		return
+	}
+	recordAudit(r, place.HouseID, models.AuditCreate, "place", place.ID, nil, place)
+
+	respondWithPlace(w, r, place.ID, http.StatusCreated)
+}
+
+// GetPlaces lists and searches places. q matches the name and the alternate and
+// historical names, so "Batavia" finds Jakarta.
+func GetPlaces(w http.ResponseWriter, r *http.Request) {
+	// Only places in houses the caller can reach
+	query := config.DB.Where("house_id IN (?)", accessibleHouseIDs(r, models.RoleViewer))
+
+	params := r.URL.Query()
+	for _, filter := range []string{"house_id", "parent_id"} {
+		if value := params.Get(filter); value != "" {
+			id, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidQuery, "Invalid "+filter)
+				return
+			}
+			query = query.Where(filter+" = ?", uint(id))
+		}
+	}
+	if placeType := params.Get("place_type"); placeType != "" {
+		query = query.Where("place_type = ?", placeType)
+	}
+	if q := strings.TrimSpace(params.Get("q")); q != "" {
+		pattern := "%" + escapeLike(q) + "%"
+		query = query.Where("name ILIKE ? OR id IN (?)", pattern,
+			config.DB.Model(&models.PlaceName{}).Select("place_id").Where("name ILIKE ?", pattern))
+	}
+
+	var places []models.Place
+	if err := query.Preload("Names").Order("name, id").Find(&places).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to fetch places")
+		return
+	}
+	if err := fillPlacePaths(places); err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to fetch places")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(places)
+}
+
+// escapeLike quotes the wildcards of a LIKE pattern
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+func GetPlace(w http.ResponseWriter, r *http.Request) {
+	id, ok := parsePlacePath(w, r)
+	if !ok {
+		return
+	}
+
+	var place models.Place
+	if err := config.DB.Where("house_id IN (?)", accessibleHouseIDs(r, models.RoleViewer)).Select("id").First(&place, id).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodePlaceNotFound, "Place not found")
+		return
+	}
+
+	respondWithPlace(w, r, place.ID, http.StatusOK)
+}
+
+func UpdatePlace(w http.ResponseWriter, r *http.Request) {
+	id, ok := parsePlacePath(w, r)
+	if !ok {
+		return
+	}
+
+	var input UpdatePlaceInput
+	if !decodeInput(w, r, &input) {
+		return
+	}
+
+	var place models.Place
+	if err := config.DB.Where("house_id IN (?)", accessibleHouseIDs(r, models.RoleViewer)).
+		Preload("Names").First(&place, id).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodePlaceNotFound, "Place not found")
+		return
+	}
+
+	if !requireHouseRole(w, r, place.HouseID, models.RoleEditor) {
+		return
+	}
+
+	if !checkPlaceParent(w, r, place.HouseID, place.ID, input.PlaceType, input.ParentID) {
+		return
+	}
+	// Places within this one must stay smaller than it
+	if hasLargerChildren(place.ID, input.PlaceType) {
+		WriteErrorDetails(w, r, http.StatusUnprocessableEntity, ErrCodeValidationFailed, "Validation failed",
+			[]FieldError{{Field: "place_type", Message: "must be larger than the places within it"}})
+		return
+	}
+
+	before := place
+	place.Name = input.Name
+	place.PlaceType = input.PlaceType
+	place.ParentID = input.ParentID
+	place.Latitude = input.Latitude
+	place.Longitude = input.Longitude
+	names := placeNames(input.Names)
+
+	// The names are replaced as a whole
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Names").Save(&place).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("place_id = ?", place.ID).Delete(&models.PlaceName{}).Error; err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			return nil
+		}
+		for i := range names {
+			names[i].PlaceID = place.ID
+		}
+		return tx.Create(&names).Error
+	})
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to update place")
+		return
+	}
+	place.Names = names
+	recordAudit(r, place.HouseID, models.AuditUpdate, "place", place.ID, before, place)
+
+	respondWithPlace(w, r, place.ID, http.StatusOK)
+}
+
+// hasLargerChildren reports whether a place directly contains places that a
+// place of placeType could not contain
+func hasLargerChildren(placeID uint, placeType string) bool {
+	var smaller []string
+	for _, t := range models.PlaceTypes {
+		if models.PlaceContains(placeType, t) {
+			smaller = append(smaller, t)
+		}
+	}
+
+	query := config.DB.Model(&models.Place{}).Where("parent_id = ?", placeID)
+	if len(smaller) > 0 {
+		query = query.Where("place_type NOT IN ?", smaller)
+	}
+	var count int64
+	query.Count(&count)
+	return count > 0
+}
+
+// DeletePlace deletes a place. The places within it move up to its parent, and
+// persons and events linked to it keep their other details.
+func DeletePlace(w http.ResponseWriter, r *http.Request) {
+	id, ok := parsePlacePath(w, r)
+	if !ok {
+		return
+	}
+
+	var place models.Place
+	if err := config.DB.Where("house_id IN (?)", accessibleHouseIDs(r, models.RoleViewer)).
+		Preload("Names").First(&place, id).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodePlaceNotFound, "Place not found")
+		return
+	}
+
+	if !requireHouseRole(w, r, place.HouseID, models.RoleEditor) {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := unlinkPlace(tx, place.ID, place.ParentID); err != nil {
+			return err
+		}
+		if err := tx.Where("place_id = ?", place.ID).Delete(&models.PlaceName{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&place).Error
+	})
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to delete place")
+		return
+	}
+	recordAudit(r, place.HouseID, models.AuditDelete, "place", place.ID, place, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Place deleted successfully",
+	})
+}
+
+// unlinkPlace points everything that refers to a place at replacementID
+// instead, which may be nil. Columns are updated directly so that no save
+// hooks run.
+func unlinkPlace(tx *gorm.DB, placeID uint, replacementID *uint) error {
+	for _, link := range []struct {
+		model  interface{}
+		column string
+	}{
+		{&models.Place{}, "parent_id"},
+		{&models.Person{}, "birth_place_id"},
+		{&models.Person{}, "death_place_id"},
+		{&models.Event{}, "place_id"},
+	} {
+		if err := tx.Model(link.model).Where(link.column+" = ?", placeID).UpdateColumn(link.column, replacementID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MergePlace folds a duplicate into the place in the URL: persons, events and
+// places within the duplicate move over, its names become alternate names and
+// the duplicate is deleted.
+func MergePlace(w http.ResponseWriter, r *http.Request) {
+	id, ok := parsePlacePath(w, r)
+	if !ok {
+		return
+	}
+
+	var input MergePlaceInput
+	if !decodeInput(w, r, &input) {
+		return
+	}
+
+	var place models.Place
+	if err := config.DB.Where("house_id IN (?)", accessibleHouseIDs(r, models.RoleViewer)).
+		Preload("Names").First(&place, id).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodePlaceNotFound, "Place not found")
+		return
+	}
+
+	if !requireHouseRole(w, r, place.HouseID, models.RoleEditor) {
+		return
+	}
+
+	var duplicate models.Place
+	if err := config.DB.Preload("Names").First(&duplicate, input.DuplicateID).Error; err != nil {
+		WriteError(w, r, http.StatusBadRequest, ErrCodePlaceNotFound, "Duplicate place not found")
+		return
+	}
+	if duplicate.HouseID != place.HouseID {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeHouseMismatch, "Both places must belong to the same house")
+		return
+	}
+	var message string
+	if duplicate.ID == place.ID {
+		message = "must differ from the place it is merged into"
+	} else if withinPlace(place.ID, duplicate.ID) {
+		message = "must not contain the place it is merged into"
+	} else {
+		if hasLargerChildren(duplicate.ID, place.PlaceType) {
+			message = "must not contain places as large as the place it is merged into"
+		}
+	}
+	if message != "" {
+		WriteErrorDetails(w, r, http.StatusUnprocessableEntity, ErrCodeValidationFailed, "Validation failed",
+			[]FieldError{{Field: "duplicate_id", Message: message}})
+		return
+	}
+
+	before := place
+	if place.Latitude == nil && duplicate.Latitude != nil {
+		place.Latitude, place.Longitude = duplicate.Latitude, duplicate.Longitude
+	}
+
+	// Keep every name once: the duplicate's own name becomes an alternate one
+	known := map[string]bool{strings.ToLower(place.Name): true}
+	for _, name := range place.Names {
+		known[strings.ToLower(name.Name)] = true
+	}
+	var added []models.PlaceName
+	for _, name := range append([]models.PlaceName{{Name: duplicate.Name, Kind: models.PlaceNameAlternate}}, duplicate.Names...) {
+		if known[strings.ToLower(name.Name)] {
+			continue
+		}
+		known[strings.ToLower(name.Name)] = true
+		added = append(added, models.PlaceName{PlaceID: place.ID, Name: name.Name, Kind: name.Kind,
+			Language: name.Language, From: name.From, To: name.To})
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := unlinkPlace(tx, duplicate.ID, &place.ID); err != nil {
+			return err
+		}
+		if err := tx.Omit("Names").Save(&place).Error; err != nil {
+			return err
+		}
+		if len(added) > 0 {
+			if err := tx.Create(&added).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("place_id = ?", duplicate.ID).Delete(&models.PlaceName{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&duplicate).Error
+	})
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to merge places")
+		return
+	}
+	place.Names = append(place.Names, added...)
+	recordAudit(r, duplicate.HouseID, models.AuditDelete, "place", duplicate.ID, duplicate, nil)
+	recordAudit(r, place.HouseID, models.AuditUpdate, "place", place.ID, before, place)
+
+	respondWithPlace(w, r, place.ID, http.StatusOK)
+}
+
+// respondWithPlace writes one place with its names and path
+func respondWithPlace(w http.ResponseWriter, r *http.Request, id uint, status int) {
+	var places []models.Place
+	if err := config.DB.Preload("Names").Where("id = ?", id).Find(&places).Error; err != nil || len(places) == 0 {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to fetch place")
+		return
+	}
+	if err := fillPlacePaths(places); err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to fetch place")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(places[0])
+}
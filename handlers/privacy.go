@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gofamtree/config"
+	"gofamtree/models"
+	"net/http"
+	"time"
+)
+
+// privacyFilter redacts living persons for callers below a house's
+// privacy_min_role. House policies and roles are looked up once per request.
+type privacyFilter struct {
+	r        *http.Request
+	now      time.Time
+	policies map[uint]housePrivacy
+}
+
+type housePrivacy struct {
+	fullAccess  bool // caller may see living persons in full
+	livingYears int
+}
+
+func newPrivacyFilter(r *http.Request) *privacyFilter {
+	return &privacyFilter{r: r, now: time.Now(), policies: map[uint]housePrivacy{}}
+}
+
+func (f *privacyFilter) policy(houseID uint) housePrivacy {
+	if policy, ok := f.policies[houseID]; ok {
+		return policy
+	}
+
+	// Unknown houses fall back to the strictest policy
+	policy := housePrivacy{livingYears: models.DefaultPrivacyLivingYears}
+	var house models.House
+	if err := config.DB.Select("id", "privacy_min_role", "privacy_living_years").First(&house, houseID).Error; err == nil {
+		policy.livingYears = house.PrivacyLivingYears
+		policy.fullAccess = hasRole(houseRole(f.r, houseID), house.PrivacyMinRole)
+	}
+	f.policies[houseID] = policy
+	return policy
+}
+
+// canSeePrivate reports whether the caller may see a person's private fields
+func (f *privacyFilter) canSeePrivate(person *models.Person) bool {
+	policy := f.policy(person.HouseID)
+	return policy.fullAccess || !person.PresumedLiving(f.now, policy.livingYears)
+}
+
+func (f *privacyFilter) redact(person *models.Person) {
+	if person.ID != 0 && !f.canSeePrivate(person) {
+		person.Redact()
+	}
+}
+
+// person redacts a person where needed and fills in their status and lifespan
+func (f *privacyFilter) person(person *models.Person) {
+	if person.ID == 0 {
+		return
+	}
+	f.redact(person)
+	person.FillLifespan(f.now, f.policy(person.HouseID).livingYears)
+}
+
+func (f *privacyFilter) persons(persons []models.Person) {
+	for i := range persons {
+		f.person(&persons[i])
+	}
+}
+
+func (f *privacyFilter) relation(relation *models.Relation) {
+	f.person(&relation.Person)
+	f.person(&relation.RelatedTo)
+}
+
+func (f *privacyFilter) relations(relations []models.Relation) {
+	for i := range relations {
+		f.relation(&relations[i])
+	}
+}
+
+// canSeeEvent reports whether the caller may see an event's private fields,
+// which needs them to see those of every participant. Participants that were
+// not preloaded are looked up, and any that cannot be found, such as the
+// deleted person in an audit snapshot, withhold the event.
+func (f *privacyFilter) canSeeEvent(event *models.Event) bool {
+	if f.policy(event.HouseID).fullAccess {
+		return true
+	}
+
+	var ids []uint
+	for _, participant := range event.Participants {
+		if participant.Person == nil {
+			ids = append(ids, participant.PersonID)
+		} else if participant.Person.ID != 0 && !f.canSeePrivate(participant.Person) {
+			return false
+		}
+	}
+	if len(ids) == 0 {
+		return true
+	}
+
+	var persons []models.Person
+	if err := config.DB.Select("id", "house_id", "dob", "dod", "is_living").Where("id IN ?", ids).Find(&persons).Error; err != nil || len(persons) < len(ids) {
+		return false
+	}
+	for i := range persons {
+		if !f.canSeePrivate(&persons[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// event redacts an event and its participants where needed
+func (f *privacyFilter) event(event *models.Event) {
+	if !f.canSeeEvent(event) {
+		event.Redact()
+	}
+	for i := range event.Participants {
+		if person := event.Participants[i].Person; person != nil {
+			f.person(person)
+		}
+	}
+}
+
+func (f *privacyFilter) events(events []models.Event) {
+	for i := range events {
+		f.event(&events[i])
+	}
+}
+
+// auditEntry redacts person and event snapshots in an audit row, since an actor
+// may be shown their own changes to a house whose living persons they cannot see
+func (f *privacyFilter) auditEntry(entry *models.AuditLog) {
+	if (entry.EntityType != "person" && entry.EntityType != "event") || entry.HouseID == nil || f.policy(*entry.HouseID).fullAccess {
+		return
+	}
+	for _, snapshot := range []*models.JSON{&entry.Before, &entry.After} {
+		if len(*snapshot) == 0 {
+			continue
+		}
+		if entry.EntityType == "event" {
+			var event models.Event
+			if err := json.Unmarshal(*snapshot, &event); err != nil {
+				*snapshot = nil
+				continue
+			}
+			if !f.canSeeEvent(&event) {
+				event.Redact()
+			}
+			*snapshot = auditSnapshot(event)
+			continue
+		}
+
+		var person models.Person
+		if err := json.Unmarshal(*snapshot, &person); err != nil {
+			*snapshot = nil
+			continue
+		}
+		f.redact(&person)
+		*snapshot = auditSnapshot(person)
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"gofamtree/config"
+	"gofamtree/models"
+	"gofamtree/utils"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CreateRegistrationInviteInput struct {
+	Email string `json:"email"` // optional; the invite is mailed to this address and locked to it
+}
+
+func (input CreateRegistrationInviteInput) validate(v *validator) {
+	v.email("email", input.Email)
+}
+
+type CreateRegistrationInviteResponse struct {
+	Message string                    `json:"message"`
+	Token   string                    `json:"token"` // Only ever returned once
+	URL     string                    `json:"url,omitempty"`
+	Invite  models.RegistrationInvite `json:"invite"`
+}
+
+// CreateRegistrationInvite issues a single-use token that lets one person register
+func CreateRegistrationInvite(w http.ResponseWriter, r *http.Request) {
+	if !requireSuperadmin(w, r) {
+		return
+	}
+
+	var input CreateRegistrationInviteInput
+	if !decodeInput(w, r, &input) {
+		return
+	}
+
+	var email *string
+	if input.Email != "" {
+		email = &input.Email
+	}
+
+	token, err := utils.RandomToken()
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to generate invite")
+		return
+	}
+
+	createdBy := currentAdminID(r)
+	invite := models.RegistrationInvite{
+		TokenHash: utils.HashToken(token),
+		Email:     email,
+		CreatedBy: &createdBy,
+		ExpiresAt: time.Now().Add(config.RegistrationInviteTTL),
+		CreatedAt: time.Now(),
+	}
+	if err := config.DB.Create(&invite).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to create invite")
+		return
+	}
+
+	inviteURL := ""
+	if config.AppBaseURL != "" {
+		inviteURL = fmt.Sprintf("%s/register?invite=%s", config.AppBaseURL, url.QueryEscape(token))
+	}
+	if email != nil {
+		if err := sendRegistrationInvite(*email, token, inviteURL); err != nil {
+			log.Printf("Failed to send registration invite %d: %v", invite.ID, err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreateRegistrationInviteResponse{
+		Message: "Invite created. Share the token or link now; it will not be shown again.",
+		Token:   token,
+		URL:     inviteURL,
+		Invite:  invite,
+	})
+}
+
+func sendRegistrationInvite(to, token, inviteURL string) error {
+	body := "Hello,\n\nYou have been invited to create a GoFamTree account.\n"
+	if inviteURL != "" {
+		body += fmt.Sprintf("Open this link to register:\n\n%s\n", inviteURL)
+	} else {
+		body += fmt.Sprintf("Register with this invite token:\n\n%s\n", token)
+	}
+	body += fmt.Sprintf("\nThe invite expires in %s and can only be used once.\n", config.RegistrationInviteTTL)
+
+	return utils.Mailer.Send(utils.Mail{
+		To:      to,
+		Subject: "You're invited to GoFamTree",
+		Body:    body,
+	})
+}
+
+func GetRegistrationInvites(w http.ResponseWriter, r *http.Request) {
+	if !requireSuperadmin(w, r) {
+		return
+	}
+
+	var invites []models.RegistrationInvite
+	if err := config.DB.Order("created_at DESC").Find(&invites).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to fetch invites")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invites)
+}
+
+// RevokeRegistrationInvite deletes an invite that has not been used yet
+func RevokeRegistrationInvite(w http.ResponseWriter, r *http.Request) {
+	if !requireSuperadmin(w, r) {
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/admin/invites/")
+	id, err := strconv.ParseUint(path, 10, 32)
+	if err != nil {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid invite ID")
+		return
+	}
+
+	result := config.DB.Where("used_at IS NULL").Delete(&models.RegistrationInvite{}, uint(id))
+	if result.Error != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to revoke invite")
+		return
+	}
+	if result.RowsAffected == 0 {
+		WriteError(w, r, http.StatusNotFound, ErrCodeInviteNotFound, "Invite not found or already used")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Invite revoked successfully",
+	})
+}
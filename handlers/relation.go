@@ -21,39 +21,63 @@ type UpdateRelationInput struct {
 	RelationType string `json:"relation_type"`
 }
 
+func (input CreateRelationInput) validate(v *validator) {
+	v.requiredID("house_id", input.HouseID)
+	v.requiredID("person_id", input.PersonID)
+	v.requiredID("related_to_id", input.RelatedToID)
+	if input.PersonID != 0 && input.PersonID == input.RelatedToID {
+		v.add("related_to_id", "must differ from person_id")
+	}
+	validateRelationType(v, input.RelationType)
+}
+
+func (input UpdateRelationInput) validate(v *validator) {
+	validateRelationType(v, input.RelationType)
+}
+
+func validateRelationType(v *validator, relationType string) {
+	v.required("relation_type", relationType)
+	v.oneOf("relation_type", relationType, "parent", "spouse", "sibling")
+}
+
 type FamilyTreeResponse struct {
 	House     models.House     `json:"house"`
 	Persons   []models.Person  `json:"persons"`
 	Relations []models.Relation `json:"relations"`
+	Events    []models.Event    `json:"events"`
+	Places    []models.Place    `json:"places"`
 }
 
 func CreateRelation(w http.ResponseWriter, r *http.Request) {
 	var input CreateRelationInput
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
+	if !decodeInput(w, r, &input) {
 		return
 	}
 
-	// Validate that house exists
-	var house models.House
-	if err := config.DB.First(&house, input.HouseID).Error; err != nil {
-		http.Error(w, "House not found", http.StatusBadRequest)
+	// Validate that house exists and the caller may edit it
+	role := houseRole(r, input.HouseID)
+	if role == "" {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeHouseNotFound, "House not found")
+		return
+	}
+	if !hasRole(role, models.RoleEditor) {
+		WriteError(w, r, http.StatusForbidden, ErrCodeInsufficientRole, "Requires editor role on this house")
 		return
 	}
 
 	// Validate that both persons exist and belong to the same house
 	var person, relatedTo models.Person
 	if err := config.DB.First(&person, input.PersonID).Error; err != nil {
-		http.Error(w, "Person not found", http.StatusBadRequest)
+		WriteError(w, r, http.StatusBadRequest, ErrCodePersonNotFound, "Person not found")
 		return
 	}
 	if err := config.DB.First(&relatedTo, input.RelatedToID).Error; err != nil {
-		http.Error(w, "Related person not found", http.StatusBadRequest)
+		WriteError(w, r, http.StatusBadRequest, ErrCodePersonNotFound, "Related person not found")
 		return
 	}
 
 	if person.HouseID != input.HouseID || relatedTo.HouseID != input.HouseID {
-		http.Error(w, "Both persons must belong to the specified house", http.StatusBadRequest)
+		WriteError(w, r, http.StatusBadRequest, ErrCodeHouseMismatch, "Both persons must belong to the specified house")
 		return
 	}
 
@@ -61,13 +85,7 @@ func CreateRelation(w http.ResponseWriter, r *http.Request) {
 	var existingRelation models.Relation
 	if err := config.DB.Where("person_id = ? AND related_to_id = ? AND relation_type = ?", 
 		input.PersonID, input.RelatedToID, input.RelationType).First(&existingRelation).Error; err == nil {
-		http.Error(w, "Relation already exists", http.StatusConflict)
-		return
-	}
-
-	// Prevent self-relation
-	if input.PersonID == input.RelatedToID {
-		http.Error(w, "Cannot create relation with self", http.StatusBadRequest)
+		WriteError(w, r, http.StatusConflict, ErrCodeRelationExists, "Relation already exists")
 		return
 	}
 
@@ -80,12 +98,14 @@ func CreateRelation(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := config.DB.Create(&relation).Error; err != nil {
-		http.Error(w, "Failed to create relation", http.StatusInternalServerError)
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to create relation")
 		return
 	}
+	recordAudit(r, relation.HouseID, models.AuditCreate, "relation", relation.ID, nil, relation)
 
 	// Load relationships
 	config.DB.Preload("House").Preload("Person").Preload("RelatedTo").First(&relation, relation.ID)
+	newPrivacyFilter(r).relation(&relation)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -94,23 +114,25 @@ func CreateRelation(w http.ResponseWriter, r *http.Request) {
 
 func GetRelations(w http.ResponseWriter, r *http.Request) {
 	var relations []models.Relation
+	// Only relations in houses the caller can reach
+	query := config.DB.Where("house_id IN (?)", accessibleHouseIDs(r, models.RoleViewer))
 	
 	// Optional: filter by house_id if provided as query parameter
-	houseID := r.URL.Query().Get("house_id")
-	if houseID != "" {
-		if err := config.DB.Where("house_id = ?", houseID).
-			Preload("House").Preload("Person").Preload("RelatedTo").
-			Find(&relations).Error; err != nil {
-			http.Error(w, "Failed to fetch relations", http.StatusInternalServerError)
-			return
-		}
-	} else {
-		if err := config.DB.Preload("House").Preload("Person").Preload("RelatedTo").
-			Find(&relations).Error; err != nil {
-			http.Error(w, "Failed to fetch relations", http.StatusInternalServerError)
+	if value := r.URL.Query().Get("house_id"); value != "" {
+		houseID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidQuery, "Invalid house_id")
 			return
 		}
+		query = query.Where("house_id = ?", uint(houseID))
+	}
+
+	if err := query.Preload("House").Preload("Person").Preload("RelatedTo").
+		Find(&relations).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to fetch relations")
+		return
 	}
+	newPrivacyFilter(r).relations(relations)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(relations)
@@ -121,16 +143,18 @@ func GetRelation(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/relations/")
 	id, err := strconv.ParseUint(path, 10, 32)
 	if err != nil {
-		http.Error(w, "Invalid relation ID", http.StatusBadRequest)
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid relation ID")
 		return
 	}
 
 	var relation models.Relation
-	if err := config.DB.Preload("House").Preload("Person").Preload("RelatedTo").
+	if err := config.DB.Where("house_id IN (?)", accessibleHouseIDs(r, models.RoleViewer)).
+		Preload("House").Preload("Person").Preload("RelatedTo").
 		First(&relation, uint(id)).Error; err != nil {
-		http.Error(w, "Relation not found", http.StatusNotFound)
+		WriteError(w, r, http.StatusNotFound, ErrCodeRelationNotFound, "Relation not found")
 		return
 	}
+	newPrivacyFilter(r).relation(&relation)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(relation)
@@ -141,32 +165,38 @@ func UpdateRelation(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/relations/")
 	id, err := strconv.ParseUint(path, 10, 32)
 	if err != nil {
-		http.Error(w, "Invalid relation ID", http.StatusBadRequest)
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid relation ID")
 		return
 	}
 
 	var input UpdateRelationInput
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
+	if !decodeInput(w, r, &input) {
 		return
 	}
 
 	var relation models.Relation
-	if err := config.DB.First(&relation, uint(id)).Error; err != nil {
-		http.Error(w, "Relation not found", http.StatusNotFound)
+	if err := config.DB.Where("house_id IN (?)", accessibleHouseIDs(r, models.RoleViewer)).First(&relation, uint(id)).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodeRelationNotFound, "Relation not found")
+		return
+	}
+
+	if !requireHouseRole(w, r, relation.HouseID, models.RoleEditor) {
 		return
 	}
 
 	// Update the relation type
+	before := relation
 	relation.RelationType = input.RelationType
 
 	if err := config.DB.Save(&relation).Error; err != nil {
-		http.Error(w, "Failed to update relation", http.StatusInternalServerError)
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to update relation")
 		return
 	}
+	recordAudit(r, relation.HouseID, models.AuditUpdate, "relation", relation.ID, before, relation)
 
 	// Load relationships
 	config.DB.Preload("House").Preload("Person").Preload("RelatedTo").First(&relation, relation.ID)
+	newPrivacyFilter(r).relation(&relation)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(relation)
@@ -177,20 +207,25 @@ func DeleteRelation(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/relations/")
 	id, err := strconv.ParseUint(path, 10, 32)
 	if err != nil {
-		http.Error(w, "Invalid relation ID", http.StatusBadRequest)
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid relation ID")
 		return
 	}
 
 	var relation models.Relation
-	if err := config.DB.First(&relation, uint(id)).Error; err != nil {
-		http.Error(w, "Relation not found", http.StatusNotFound)
+	if err := config.DB.Where("house_id IN (?)", accessibleHouseIDs(r, models.RoleViewer)).First(&relation, uint(id)).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodeRelationNotFound, "Relation not found")
+		return
+	}
+
+	if !requireHouseRole(w, r, relation.HouseID, models.RoleEditor) {
 		return
 	}
 
 	if err := config.DB.Delete(&relation).Error; err != nil {
-		http.Error(w, "Failed to delete relation", http.StatusInternalServerError)
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to delete relation")
 		return
 	}
+	recordAudit(r, relation.HouseID, models.AuditDelete, "relation", relation.ID, relation, nil)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
@@ -203,36 +238,67 @@ func GetFamilyTree(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/family-tree/")
 	houseID, err := strconv.ParseUint(path, 10, 32)
 	if err != nil {
-		http.Error(w, "Invalid house ID", http.StatusBadRequest)
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid house ID")
 		return
 	}
 
 	// Get the house
 	var house models.House
-	if err := config.DB.Preload("Admin").First(&house, uint(houseID)).Error; err != nil {
-		http.Error(w, "House not found", http.StatusNotFound)
+	if err := config.DB.Where("id IN (?)", accessibleHouseIDs(r, models.RoleViewer)).Preload("Admin").First(&house, uint(houseID)).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodeHouseNotFound, "House not found")
 		return
 	}
 
+	respondWithFamilyTree(w, r, house)
+}
+
+// respondWithFamilyTree writes a house with all its persons and relations
+func respondWithFamilyTree(w http.ResponseWriter, r *http.Request, house models.House) {
 	// Get all persons in the house
 	var persons []models.Person
-	if err := config.DB.Where("house_id = ?", uint(houseID)).Find(&persons).Error; err != nil {
-		http.Error(w, "Failed to fetch persons", http.StatusInternalServerError)
+	if err := config.DB.Where("house_id = ?", house.ID).Find(&persons).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to fetch persons")
 		return
 	}
 
 	// Get all relations in the house
 	var relations []models.Relation
-	if err := config.DB.Where("house_id = ?", uint(houseID)).
+	if err := config.DB.Where("house_id = ?", house.ID).
 		Preload("Person").Preload("RelatedTo").Find(&relations).Error; err != nil {
-		http.Error(w, "Failed to fetch relations", http.StatusInternalServerError)
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to fetch relations")
+		return
+	}
+
+	// Get all events in the house, in date order
+	events, err := eventsWithParticipants(config.DB.Where("house_id = ?", house.ID))
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to fetch events")
 		return
 	}
 
+	// Get all places in the house, with their enclosing places
+	var places []models.Place
+	if err := config.DB.Where("house_id = ?", house.ID).Preload("Names").Order("name, id").Find(&places).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to fetch places")
+		return
+	}
+	if err := fillPlacePaths(places); err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to fetch places")
+		return
+	}
+
+	// Living persons are redacted for callers below the house's privacy role
+	privacy := newPrivacyFilter(r)
+	privacy.persons(persons)
+	privacy.relations(relations)
+	privacy.events(events)
+
 	response := FamilyTreeResponse{
 		House:     house,
 		Persons:   persons,
 		Relations: relations,
+		Events:    events,
+		Places:    places,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
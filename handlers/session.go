@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"gofamtree/config"
+	"gofamtree/models"
+	"gofamtree/utils"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var errRefreshTokenReused = errors.New("refresh token reused")
+
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (input RefreshTokenInput) validate(v *validator) {
+	v.required("refresh_token", input.RefreshToken)
+	v.maxLength("refresh_token", input.RefreshToken, maxSecretLength)
+}
+
+// loginTokens is what a successful login or refresh hands to the client
+type loginTokens struct {
+	AccessToken      string
+	ExpiresAt        time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// startSession opens a login session for the request's device and issues its
+// first access and refresh tokens
+func startSession(r *http.Request, admin models.Admin) (loginTokens, error) {
+	var tokens loginTokens
+	now := time.Now()
+	userAgent := r.UserAgent()
+
+	session := models.Session{
+		AdminID:    admin.ID,
+		Device:     utils.DescribeUserAgent(userAgent),
+		UserAgent:  userAgent,
+		IPAddress:  utils.ClientIP(r),
+		ExpiresAt:  now.Add(config.SessionMaxAge),
+		LastUsedAt: &now,
+		CreatedAt:  now,
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+		var err error
+		tokens, err = issueSessionTokens(tx, session, now)
+		return err
+	})
+	return tokens, err
+}
+
+// issueSessionTokens creates a new refresh token for a session and signs a
+// matching access token. A refresh token never outlives its session.
+func issueSessionTokens(tx *gorm.DB, session models.Session, now time.Time) (loginTokens, error) {
+	var tokens loginTokens
+
+	secret, err := utils.RandomToken()
+	if err != nil {
+		return tokens, err
+	}
+	expiresAt := now.Add(config.RefreshTokenTTL)
+	if expiresAt.After(session.ExpiresAt) {
+		expiresAt = session.ExpiresAt
+	}
+
+	if err := tx.Create(&models.RefreshToken{
+		SessionID: session.ID,
+		TokenHash: utils.HashToken(secret),
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+	}).Error; err != nil {
+		return tokens, err
+	}
+
+	accessToken, accessExpiresAt, err := utils.GenerateToken(session.AdminID, session.ID)
+	if err != nil {
+		return tokens, err
+	}
+
+	return loginTokens{
+		AccessToken:      accessToken,
+		ExpiresAt:        accessExpiresAt,
+		RefreshToken:     secret,
+		RefreshExpiresAt: expiresAt,
+	}, nil
+}
+
+// revokeSessions logs an admin out everywhere except the given session (0 for all)
+func revokeSessions(tx *gorm.DB, adminID, exceptSessionID uint) error {
+	return tx.Model(&models.Session{}).
+		Where("admin_id = ? AND id <> ? AND revoked_at IS NULL", adminID, exceptSessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RefreshAccessToken exchanges a refresh token for a new access token and a new
+// refresh token. Each refresh token works once: presenting a used one means it
+// was copied, so the whole session is revoked and must log in again.
+func RefreshAccessToken(w http.ResponseWriter, r *http.Request) {
+	var input RefreshTokenInput
+	if !decodeInput(w, r, &input) {
+		return
+	}
+
+	now := time.Now()
+	var refreshToken models.RefreshToken
+	if err := config.DB.Where("token_hash = ?", utils.HashToken(input.RefreshToken)).First(&refreshToken).Error; err != nil {
+		WriteError(w, r, http.StatusUnauthorized, ErrCodeRefreshTokenInvalid, "Invalid or expired refresh token")
+		return
+	}
+
+	var session models.Session
+	if err := config.DB.First(&session, refreshToken.SessionID).Error; err != nil {
+		WriteError(w, r, http.StatusUnauthorized, ErrCodeRefreshTokenInvalid, "Invalid or expired refresh token")
+		return
+	}
+
+	var tokens loginTokens
+	err := errRefreshTokenReused
+	if refreshToken.UsedAt == nil {
+		if !session.Active(now) || !now.Before(refreshToken.ExpiresAt) {
+			WriteError(w, r, http.StatusUnauthorized, ErrCodeRefreshTokenInvalid, "Invalid or expired refresh token")
+			return
+		}
+
+		err = config.DB.Transaction(func(tx *gorm.DB) error {
+			// Only one request may rotate a token, even when two race
+			result := tx.Model(&models.RefreshToken{}).
+				Where("id = ? AND used_at IS NULL", refreshToken.ID).
+				Update("used_at", now)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errRefreshTokenReused
+			}
+
+			if err := tx.Model(&session).Updates(map[string]interface{}{
+				"last_used_at": now,
+				"ip_address":   utils.ClientIP(r),
+			}).Error; err != nil {
+				return err
+			}
+
+			var err error
+			tokens, err = issueSessionTokens(tx, session, now)
+			return err
+		})
+	}
+
+	if err == errRefreshTokenReused {
+		if session.RevokedAt == nil {
+			config.DB.Model(&session).Update("revoked_at", now)
+			log.Printf("Refresh token reuse detected - revoked session %d of admin %d", session.ID, session.AdminID)
+		}
+		WriteError(w, r, http.StatusUnauthorized, ErrCodeRefreshTokenReused, "Refresh token has already been used; the session has been revoked")
+		return
+	}
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to refresh token")
+		return
+	}
+
+	var admin models.Admin
+	if err := config.DB.First(&admin, session.AdminID).Error; err != nil || admin.DisabledAt != nil {
+		WriteError(w, r, http.StatusUnauthorized, ErrCodeRefreshTokenInvalid, "Invalid or expired refresh token")
+		return
+	}
+
+	writeLoginResponse(w, "Token refreshed", admin, tokens)
+}
+
+// loadSessionCaller returns the caller's own session, which API tokens do not have
+func loadSessionCaller(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	if !requireInteractiveLogin(w, r) {
+		return 0, false
+	}
+	return utils.SessionIDFromContext(r.Context()), true
+}
+
+// GetSessions lists the caller's signed-in devices, marking the current one
+func GetSessions(w http.ResponseWriter, r *http.Request) {
+	currentSessionID, ok := loadSessionCaller(w, r)
+	if !ok {
+		return
+	}
+
+	// A session whose refresh tokens have all expired or been used is idle and cannot be renewed
+	now := time.Now()
+	var sessions []models.Session
+	if err := config.DB.
+		Where("admin_id = ? AND revoked_at IS NULL AND expires_at > ?", currentAdminID(r), now).
+		Where("id = ? OR EXISTS (SELECT 1 FROM refresh_tokens WHERE refresh_tokens.session_id = sessions.id AND used_at IS NULL AND expires_at > ?)", currentSessionID, now).
+		Order("last_used_at DESC").
+		Find(&sessions).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to fetch sessions")
+		return
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// RevokeSession logs one of the caller's devices out
+func RevokeSession(w http.ResponseWriter, r *http.Request) {
+	if _, ok := loadSessionCaller(w, r); !ok {
+		return
+	}
+
+	// Extract ID from URL path
+	path := strings.TrimPrefix(r.URL.Path, "/admin/sessions/")
+	id, err := strconv.ParseUint(path, 10, 32)
+	if err != nil {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid session ID")
+		return
+	}
+
+	var session models.Session
+	if err := config.DB.Where("admin_id = ?", currentAdminID(r)).First(&session, uint(id)).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodeSessionNotFound, "Session not found")
+		return
+	}
+
+	if session.RevokedAt == nil {
+		if err := config.DB.Model(&session).Update("revoked_at", time.Now()).Error; err != nil {
+			WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to revoke session")
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Session revoked successfully",
+	})
+}
+
+// RevokeOtherSessions logs the caller out on every device but the current one
+func RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	currentSessionID, ok := loadSessionCaller(w, r)
+	if !ok {
+		return
+	}
+
+	if err := revokeSessions(config.DB, currentAdminID(r), currentSessionID); err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to revoke sessions")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Signed out of all other sessions",
+	})
+}
+
+// Logout ends the current session; its access and refresh tokens stop working at once
+func Logout(w http.ResponseWriter, r *http.Request) {
+	currentSessionID, ok := loadSessionCaller(w, r)
+	if !ok {
+		return
+	}
+
+	if err := config.DB.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", currentSessionID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to log out")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Logged out successfully",
+	})
+}
@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"gofamtree/config"
+	"gofamtree/models"
+	"gofamtree/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ShareLinkPasswordHeader carries the password of a protected share link
+const ShareLinkPasswordHeader = "X-Share-Password"
+
+type CreateShareLinkInput struct {
+	Name      string `json:"name"`
+	ExpiresAt string `json:"expires_at"` // YYYY-MM-DD or RFC 3339 (optional)
+	Password  string `json:"password"`   // optional; viewers must send it in X-Share-Password
+}
+
+func (input CreateShareLinkInput) validate(v *validator) {
+	v.maxLength("name", input.Name, maxLabelLength)
+	v.futureTime("expires_at", input.ExpiresAt)
+	v.maxLength("password", input.Password, maxSecretLength)
+}
+
+type CreateShareLinkResponse struct {
+	Message   string           `json:"message"`
+	Token     string           `json:"token"` // Only ever returned once
+	URL       string           `json:"url,omitempty"`
+	ShareLink models.ShareLink `json:"share_link"`
+}
+
+// parseShareLinkPath extracts the IDs from /houses/{id}/share-links[/{link_id}].
+// linkID is 0 when the path stops at /share-links.
+func parseShareLinkPath(path string) (houseID uint, linkID uint, ok bool) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(path, "/houses/"), "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[1] != "share-links" {
+		return 0, 0, false
+	}
+
+	id, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+	if len(parts) == 2 {
+		return uint(id), 0, true
+	}
+
+	link, err := strconv.ParseUint(parts[2], 10, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+	return uint(id), uint(link), true
+}
+
+func shareLinkKey(id uint) string {
+	return "share:" + strconv.FormatUint(uint64(id), 10)
+}
+
+// shareIPKey counts a client's share password failures apart from its admin
+// logins, so guessing one never locks the other out
+func shareIPKey(ip string) string {
+	return "share-ip:" + ip
+}
+
+// CreateShareLink issues a read-only link to a house (owner only)
+func CreateShareLink(w http.ResponseWriter, r *http.Request) {
+	houseID, _, ok := parseShareLinkPath(r.URL.Path)
+	if !ok {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid house ID")
+		return
+	}
+
+	if !requireHouseRole(w, r, houseID, models.RoleOwner) {
+		return
+	}
+
+	var input CreateShareLinkInput
+	if !decodeInput(w, r, &input) {
+		return
+	}
+
+	var expiresAt *time.Time
+	if input.ExpiresAt != "" {
+		parsed, _ := parseTimestamp(input.ExpiresAt)
+		expiresAt = &parsed
+	}
+
+	var passwordHash *string
+	if input.Password != "" {
+		hashed, err := utils.HashPassword(input.Password)
+		if err != nil {
+			WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to hash password")
+			return
+		}
+		passwordHash = &hashed
+	}
+
+	token, err := utils.RandomToken()
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to generate link")
+		return
+	}
+
+	createdBy := currentAdminID(r)
+	link := models.ShareLink{
+		HouseID:      houseID,
+		Name:         strings.TrimSpace(input.Name),
+		TokenHash:    utils.HashToken(token),
+		PasswordHash: passwordHash,
+		HasPassword:  passwordHash != nil,
+		ExpiresAt:    expiresAt,
+		CreatedBy:    &createdBy,
+		CreatedAt:    time.Now(),
+	}
+	if err := config.DB.Create(&link).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to create share link")
+		return
+	}
+	recordAudit(r, houseID, models.AuditCreate, "share_link", link.ID, nil, link)
+
+	shareURL := ""
+	if config.AppBaseURL != "" {
+		shareURL = fmt.Sprintf("%s/shared/%s", config.AppBaseURL, token)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreateShareLinkResponse{
+		Message:   "Share link created - store it now, it will not be shown again",
+		Token:     token,
+		URL:       shareURL,
+		ShareLink: link,
+	})
+}
+
+// GetShareLinks lists a house's share links with their usage (owner only)
+func GetShareLinks(w http.ResponseWriter, r *http.Request) {
+	houseID, _, ok := parseShareLinkPath(r.URL.Path)
+	if !ok {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid house ID")
+		return
+	}
+
+	if !requireHouseRole(w, r, houseID, models.RoleOwner) {
+		return
+	}
+
+	var links []models.ShareLink
+	if err := config.DB.Where("house_id = ?", houseID).Order("id").Find(&links).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to fetch share links")
+		return
+	}
+	for i := range links {
+		links[i].HasPassword = links[i].PasswordHash != nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(links)
+}
+
+// RevokeShareLink stops a share link from working (owner only)
+func RevokeShareLink(w http.ResponseWriter, r *http.Request) {
+	houseID, linkID, ok := parseShareLinkPath(r.URL.Path)
+	if !ok || linkID == 0 {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid share link ID")
+		return
+	}
+
+	if !requireHouseRole(w, r, houseID, models.RoleOwner) {
+		return
+	}
+
+	var link models.ShareLink
+	if err := config.DB.Where("house_id = ?", houseID).First(&link, linkID).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodeShareLinkNotFound, "Share link not found")
+		return
+	}
+	link.HasPassword = link.PasswordHash != nil
+
+	if link.RevokedAt == nil {
+		before := link
+		now := time.Now()
+		link.RevokedAt = &now
+		if err := config.DB.Model(&link).Update("revoked_at", now).Error; err != nil {
+			WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to revoke share link")
+			return
+		}
+		recordAudit(r, houseID, models.AuditUpdate, "share_link", link.ID, before, link)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Share link revoked successfully",
+	})
+}
+
+// openShareLink resolves the token in /shared/{token}/... and checks the link's
+// password. Every successful use is counted.
+func openShareLink(w http.ResponseWriter, r *http.Request) (models.ShareLink, bool) {
+	var link models.ShareLink
+	token, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/shared/"), "/")
+
+	now := time.Now()
+	if token == "" || config.DB.Where("token_hash = ?", utils.HashToken(token)).First(&link).Error != nil || !link.Active(now) {
+		WriteError(w, r, http.StatusNotFound, ErrCodeShareLinkNotFound, "Share link not found or expired")
+		return link, false
+	}
+
+	if link.PasswordHash != nil {
+		password := r.Header.Get(ShareLinkPasswordHeader)
+		if password == "" {
+			WriteError(w, r, http.StatusUnauthorized, ErrCodeSharePasswordRequired, "This share link requires a password")
+			return link, false
+		}
+
+		// Throttle guessing like admin logins, per link and per client
+		linkKey, clientKey := shareLinkKey(link.ID), shareIPKey(utils.ClientIP(r))
+		wait := loginLimiter.wait(linkKey, now)
+		if ipWait := loginLimiter.wait(clientKey, now); ipWait > wait {
+			wait = ipWait
+		}
+		if wait > 0 {
+			tooManyAttempts(w, r, wait)
+			return link, false
+		}
+
+		if !utils.CheckPasswordHash(password, *link.PasswordHash) {
+			loginLimiter.fail(linkKey, config.LoginMaxFailures, now)
+			loginLimiter.fail(clientKey, config.LoginMaxIPFailures, now)
+			WriteError(w, r, http.StatusUnauthorized, ErrCodeSharePasswordInvalid, "Invalid share link password")
+			return link, false
+		}
+		loginLimiter.reset(linkKey)
+	}
+
+	config.DB.Model(&link).UpdateColumns(map[string]interface{}{
+		"use_count":    gorm.Expr("use_count + 1"),
+		"last_used_at": now,
+	})
+	return link, true
+}
+
+// GetSharedFamilyTree returns the family tree behind a share link. Share link
+// viewers hold no role, so living persons are always redacted.
+func GetSharedFamilyTree(w http.ResponseWriter, r *http.Request) {
+	link, ok := openShareLink(w, r)
+	if !ok {
+		return
+	}
+
+	var house models.House
+	if err := config.DB.First(&house, link.HouseID).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodeHouseNotFound, "House not found")
+		return
+	}
+
+	respondWithFamilyTree(w, r, house)
+}
+
+// GetSharedPersons lists the persons of the house behind a share link
+func GetSharedPersons(w http.ResponseWriter, r *http.Request) {
+	link, ok := openShareLink(w, r)
+	if !ok {
+		return
+	}
+
+	var persons []models.Person
+	if err := config.DB.Where("house_id = ?", link.HouseID).Order("id").Find(&persons).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to fetch persons")
+		return
+	}
+	newPrivacyFilter(r).persons(persons)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(persons)
+}
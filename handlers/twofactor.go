@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gofamtree/config"
+	"gofamtree/models"
+	"gofamtree/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const recoveryCodeCount = 10
+
+type TOTPCodeInput struct {
+	Code string `json:"code"`
+}
+
+type DisableTOTPInput struct {
+	Password     string `json:"password"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type LoginTOTPInput struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code"`          // from the authenticator app
+	RecoveryCode string `json:"recovery_code"` // alternatively, one unused recovery code
+}
+
+func (input TOTPCodeInput) validate(v *validator) {
+	v.required("code", input.Code)
+	v.maxLength("code", input.Code, maxSecretLength)
+}
+
+func (input DisableTOTPInput) validate(v *validator) {
+	v.required("password", input.Password)
+	v.maxLength("password", input.Password, maxSecretLength)
+	validateSecondFactor(v, input.Code, input.RecoveryCode)
+}
+
+func (input LoginTOTPInput) validate(v *validator) {
+	v.required("mfa_token", input.MFAToken)
+	v.maxLength("mfa_token", input.MFAToken, maxSecretLength)
+	validateSecondFactor(v, input.Code, input.RecoveryCode)
+}
+
+// validateSecondFactor requires a TOTP code or a recovery code
+func validateSecondFactor(v *validator, code, recoveryCode string) {
+	if strings.TrimSpace(code) == "" && strings.TrimSpace(recoveryCode) == "" {
+		v.add("code", "is required unless recovery_code is given")
+	}
+	v.maxLength("code", code, maxSecretLength)
+	v.maxLength("recovery_code", recoveryCode, maxSecretLength)
+}
+
+type TOTPSetupResponse struct {
+	Message         string `json:"message"`
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type RecoveryCodesResponse struct {
+	Message       string   `json:"message"`
+	RecoveryCodes []string `json:"recovery_codes"` // Only ever returned once
+}
+
+// loadInteractiveAdmin loads the caller for 2FA management, which API tokens may not do
+func loadInteractiveAdmin(w http.ResponseWriter, r *http.Request) (models.Admin, bool) {
+	var admin models.Admin
+	if !requireInteractiveLogin(w, r) {
+		return admin, false
+	}
+	if err := config.DB.First(&admin, currentAdminID(r)).Error; err != nil {
+		WriteError(w, r, http.StatusNotFound, ErrCodeAdminNotFound, "Admin not found")
+		return admin, false
+	}
+	return admin, true
+}
+
+// SetupTOTP generates a new secret. 2FA stays off until EnableTOTP confirms a first code.
+func SetupTOTP(w http.ResponseWriter, r *http.Request) {
+	admin, ok := loadInteractiveAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	if admin.TOTPEnabled {
+		WriteError(w, r, http.StatusConflict, ErrCodeTwoFactorEnabled, "Two-factor authentication is already enabled")
+		return
+	}
+
+	secret, err := utils.NewTOTPSecret()
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to generate secret")
+		return
+	}
+
+	if err := config.DB.Model(&admin).Updates(map[string]interface{}{
+		"totp_secret":       secret,
+		"totp_last_counter": 0,
+	}).Error; err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to save secret")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TOTPSetupResponse{
+		Message:         "Scan the provisioning URI, then confirm a code at /admin/2fa/enable",
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(config.TOTPIssuer, admin.Username, secret),
+	})
+}
+
+// EnableTOTP verifies the first code from the authenticator and hands out recovery codes
+func EnableTOTP(w http.ResponseWriter, r *http.Request) {
+	admin, ok := loadInteractiveAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var input TOTPCodeInput
+	if !decodeInput(w, r, &input) {
+		return
+	}
+
+	if admin.TOTPEnabled {
+		WriteError(w, r, http.StatusConflict, ErrCodeTwoFactorEnabled, "Two-factor authentication is already enabled")
+		return
+	}
+	if admin.TOTPSecret == "" {
+		WriteError(w, r, http.StatusBadRequest, ErrCodeTwoFactorSetupMissing, "Call /admin/2fa/setup first")
+		return
+	}
+
+	if !acceptTOTPCode(admin, input.Code) {
+		WriteError(w, r, http.StatusUnauthorized, ErrCodeTwoFactorCodeInvalid, "Invalid two-factor code")
+		return
+	}
+
+	var codes []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&admin).Update("totp_enabled", true).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, admin.ID)
+		return err
+	})
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to enable two-factor authentication")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RecoveryCodesResponse{
+		Message:       "Two-factor authentication enabled - store these recovery codes somewhere safe",
+		RecoveryCodes: codes,
+	})
+}
+
+func DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	admin, ok := loadInteractiveAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var input DisableTOTPInput
+	if !decodeInput(w, r, &input) {
+		return
+	}
+
+	if !admin.TOTPEnabled {
+		WriteError(w, r, http.StatusConflict, ErrCodeTwoFactorNotEnabled, "Two-factor authentication is not enabled")
+		return
+	}
+
+	// Turning 2FA off needs both factors
+	if !utils.CheckPasswordHash(input.Password, admin.Password) || !verifySecondFactor(admin, input.Code, input.RecoveryCode) {
+		WriteError(w, r, http.StatusUnauthorized, ErrCodeTwoFactorCodeInvalid, "Invalid password or two-factor code")
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&admin).Updates(map[string]interface{}{
+			"totp_enabled":      false,
+			"totp_secret":       "",
+			"totp_last_counter": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("admin_id = ?", admin.ID).Delete(&models.RecoveryCode{}).Error
+	})
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to disable two-factor authentication")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes, e.g. after most have been used
+func RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	admin, ok := loadInteractiveAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var input TOTPCodeInput
+	if !decodeInput(w, r, &input) {
+		return
+	}
+
+	if !admin.TOTPEnabled {
+		WriteError(w, r, http.StatusConflict, ErrCodeTwoFactorNotEnabled, "Two-factor authentication is not enabled")
+		return
+	}
+
+	if !acceptTOTPCode(admin, input.Code) {
+		WriteError(w, r, http.StatusUnauthorized, ErrCodeTwoFactorCodeInvalid, "Invalid two-factor code")
+		return
+	}
+
+	var codes []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, admin.ID)
+		return err
+	})
+	if err != nil {
+		WriteError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to generate recovery codes")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RecoveryCodesResponse{
+		Message:       "New recovery codes generated - the old ones no longer work",
+		RecoveryCodes: codes,
+	})
+}
+
+// AdminLoginTOTP is the second step of a 2FA login: it trades the MFA token from
+// AdminLogin plus a TOTP or recovery code for an access token
+func AdminLoginTOTP(w http.ResponseWriter, r *http.Request) {
+	var input LoginTOTPInput
+	if !decodeInput(w, r, &input) {
+		return
+	}
+
+	claims, err := utils.ParseMFAToken(input.MFAToken)
+	if err != nil {
+		WriteError(w, r, http.StatusUnauthorized, ErrCodeMFATokenInvalid, "Invalid or expired MFA token")
+		return
+	}
+
+	// Six digits are guessable without throttling
+	now := time.Now()
+	key := "mfa:" + strconv.FormatUint(uint64(claims.Subject), 10)
+	if wait := loginLimiter.wait(key, now); wait > 0 {
+		tooManyAttempts(w, r, wait)
+		return
+	}
+
+	var admin models.Admin
+	if err := config.DB.First(&admin, claims.Subject).Error; err != nil || !admin.TOTPEnabled {
+		WriteError(w, r, http.StatusUnauthorized, ErrCodeMFATokenInvalid, "Invalid or expired MFA token")
+		return
+	}
+
+	if admin.DisabledAt != nil {
+		WriteError(w, r, http.StatusForbidden, ErrCodeAccountDisabled, "Account is disabled")
+		return
+	}
+
+	if !verifySecondFactor(admin, input.Code, input.RecoveryCode) {
+		loginLimiter.fail(key, config.LoginMaxFailures, now)
+		WriteError(w, r, http.StatusUnauthorized, ErrCodeTwoFactorCodeInvalid, "Invalid two-factor code")
+		return
+	}
+
+	loginLimiter.reset(key)
+	respondWithLoginToken(w, r, admin)
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused recovery code
+func verifySecondFactor(admin models.Admin, code, recoveryCode string) bool {
+	if code != "" {
+		return acceptTOTPCode(admin, code)
+	}
+	if recoveryCode != "" {
+		return useRecoveryCode(admin.ID, recoveryCode)
+	}
+	return false
+}
+
+// acceptTOTPCode validates a code and records its time step, so the same code
+// cannot be replayed within its validity window
+func acceptTOTPCode(admin models.Admin, code string) bool {
+	counter, ok := utils.ValidateTOTPAfter(admin.TOTPSecret, code, time.Now(), admin.TOTPLastCounter)
+	if !ok {
+		return false
+	}
+
+	// The condition also holds off a concurrent request with the same code
+	result := config.DB.Model(&models.Admin{}).
+		Where("id = ? AND totp_last_counter < ?", admin.ID, counter).
+		Update("totp_last_counter", counter)
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+func useRecoveryCode(adminID uint, code string) bool {
+	result := config.DB.Model(&models.RecoveryCode{}).
+		Where("admin_id = ? AND code_hash = ? AND used_at IS NULL", adminID, utils.HashToken(utils.NormalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// replaceRecoveryCodes deletes an admin's recovery codes and stores a fresh set,
+// returning the plain codes so they can be shown once
+func replaceRecoveryCodes(tx *gorm.DB, adminID uint) ([]string, error) {
+	if err := tx.Where("admin_id = ?", adminID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := utils.NewRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		record := models.RecoveryCode{
+			AdminID:   adminID,
+			CodeHash:  utils.HashToken(utils.NormalizeRecoveryCode(code)),
+			CreatedAt: time.Now(),
+		}
+		if err := tx.Create(&record).Error; err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"gofamtree/models"
+	"io"
+	"net/http"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Field length limits, in characters
+const (
+	maxNameLength        = 200
+	maxLabelLength       = 100 // API token and share link names
+	maxUsernameLength    = 100
+	maxEmailLength       = 254
+	maxContactLength     = 500
+	maxDescriptionLength = 5000
+	maxSecretLength      = 1024 // passwords, tokens and codes
+	maxDateLength        = 100  // genealogical dates as entered
+)
+
+// maxBodyBytes caps request bodies, far above the largest valid one
+const maxBodyBytes = 1 << 20
+
+// dateLayout is the format of calendar dates in request bodies
+const dateLayout = "2006-01-02"
+
+// FieldError describes one rejected field of a request body
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// validator collects the field errors of one request body
+type validator struct {
+	errors []FieldError
+}
+
+// validatable is implemented by every *Input struct
+type validatable interface {
+	validate(v *validator)
+}
+
+func (v *validator) add(field, message string) {
+	v.errors = append(v.errors, FieldError{Field: field, Message: message})
+}
+
+func (v *validator) required(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.add(field, "is required")
+	}
+}
+
+func (v *validator) requiredID(field string, id uint) {
+	if id == 0 {
+		v.add(field, "is required")
+	}
+}
+
+func (v *validator) maxLength(field, value string, max int) {
+	if utf8.RuneCountInString(value) > max {
+		v.add(field, "must be at most "+strconv.Itoa(max)+" characters")
+	}
+}
+
+// oneOf checks an enum value; empty values are left to required
+func (v *validator) oneOf(field, value string, allowed ...string) {
+	if value == "" {
+		return
+	}
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.add(field, "must be one of "+strings.Join(allowed, ", "))
+}
+
+func (v *validator) between(field string, value, min, max int) {
+	if value < min || value > max {
+		v.add(field, "must be between "+strconv.Itoa(min)+" and "+strconv.Itoa(max))
+	}
+}
+
+// genDate checks an optional genealogical date, see models.ParseGenDate
+func (v *validator) genDate(field, value string) {
+	if value == "" {
+		return
+	}
+	if len(value) > maxDateLength {
+		v.add(field, "must be at most "+strconv.Itoa(maxDateLength)+" characters")
+		return
+	}
+	if _, err := models.ParseGenDate(value); err != nil {
+		v.add(field, err.Error())
+	}
+}
+
+// futureTime checks an optional expiry given as YYYY-MM-DD or RFC 3339
+func (v *validator) futureTime(field, value string) {
+	if value == "" {
+		return
+	}
+	parsed, err := parseTimestamp(value)
+	if err != nil {
+		v.add(field, "must be YYYY-MM-DD or RFC 3339")
+		return
+	}
+	if !parsed.After(time.Now()) {
+		v.add(field, "must be in the future")
+	}
+}
+
+// email checks an optional, bare email address
+func (v *validator) email(field, value string) {
+	if value == "" {
+		return
+	}
+	address, err := mail.ParseAddress(value)
+	if err != nil || address.Address != value {
+		v.add(field, "must be a valid email address")
+		return
+	}
+	v.maxLength(field, value, maxEmailLength)
+}
+
+// parseTimestamp accepts RFC 3339 timestamps and plain dates
+func parseTimestamp(value string) (time.Time, error) {
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		parsed, err = time.Parse(dateLayout, value)
+	}
+	return parsed, err
+}
+
+// decodeInput reads a JSON request body into input and validates it. Unknown
+// fields, values of the wrong type and failed rules answer 422 with one
+// FieldError per field in the error details; bodies that are not JSON at all
+// answer 400 and bodies over maxBodyBytes 413. It reports whether the handler
+// may go on.
+func decodeInput(w http.ResponseWriter, r *http.Request, input validatable) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+
+	v := &validator{}
+	if err := decoder.Decode(input); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		var sizeErr *http.MaxBytesError
+		if field, ok := unknownField(err); ok {
+			v.add(field, "is not a known field")
+		} else if errors.As(err, &typeErr) && typeErr.Field != "" {
+			v.add(typeErr.Field, "must be "+jsonTypeName(typeErr.Type))
+		} else if errors.As(err, &sizeErr) {
+			WriteError(w, r, http.StatusRequestEntityTooLarge, ErrCodeRequestTooLarge, "Request body too large")
+			return false
+		} else {
+			WriteError(w, r, http.StatusBadRequest, ErrCodeInvalidJSON, "Invalid input")
+			return false
+		}
+	} else if err := decoder.Decode(&json.RawMessage{}); err != io.EOF {
+		// A second value after the first would otherwise be dropped unread
+		var sizeErr *http.MaxBytesError
+		if errors.As(err, &sizeErr) {
+			WriteError(w, r, http.StatusRequestEntityTooLarge, ErrCodeRequestTooLarge, "Request body too large")
+			return false
+		}
+		v.add("body", "must hold a single JSON value")
+	} else {
+		input.validate(v)
+	}
+
+	if len(v.errors) > 0 {
+		WriteErrorDetails(w, r, http.StatusUnprocessableEntity, ErrCodeValidationFailed, "Validation failed", v.errors)
+		return false
+	}
+	return true
+}
+
+// unknownField extracts the field name from the error DisallowUnknownFields
+// produces, which encoding/json has no type for
+func unknownField(err error) (string, bool) {
+	quoted, ok := strings.CutPrefix(err.Error(), "json: unknown field ")
+	if !ok {
+		return "", false
+	}
+	field, err := strconv.Unquote(quoted)
+	if err != nil {
+		return quoted, true
+	}
+	return field, true
+}
+
+func jsonTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "a whole number"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a positive whole number"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "a list"
+	default:
+		return "an object"
+	}
+}
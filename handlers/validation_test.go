@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecodeInput(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		ok     bool
+		status int
+		code   string
+	}{
+		{"valid", `{"name_type":"birth","given_name":"Jane"}`, true, 0, ""},
+		{"not json", `{"name_type":`, false, http.StatusBadRequest, ErrCodeInvalidJSON},
+		{"unknown field", `{"name_type":"birth","given_name":"Jane","nick":"J"}`, false, http.StatusUnprocessableEntity, ErrCodeValidationFailed},
+		{"wrong type", `{"name_type":"birth","given_name":7}`, false, http.StatusUnprocessableEntity, ErrCodeValidationFailed},
+		{"failed rule", `{"name_type":"birth"}`, false, http.StatusUnprocessableEntity, ErrCodeValidationFailed},
+		{"second object", `{"name_type":"birth","given_name":"Jane"}{"given_name":"John"}`, false, http.StatusUnprocessableEntity, ErrCodeValidationFailed},
+		{"trailing garbage", `{"name_type":"birth","given_name":"Jane"} x`, false, http.StatusUnprocessableEntity, ErrCodeValidationFailed},
+		{"trailing brace", `{"name_type":"birth","given_name":"Jane"}}`, false, http.StatusUnprocessableEntity, ErrCodeValidationFailed},
+		{"trailing whitespace", "{\"name_type\":\"birth\",\"given_name\":\"Jane\"}\n", true, 0, ""},
+		{"too large", `{"name_type":"birth","given_name":"` + strings.Repeat("a", maxBodyBytes) + `"}`, false, http.StatusRequestEntityTooLarge, ErrCodeRequestTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			var input PersonNameInput
+			ok := decodeInput(rec, httptest.NewRequest("POST", "/persons/1/names", strings.NewReader(tt.body)), &input)
+			if ok != tt.ok {
+				t.Fatalf("decodeInput = %v, want %v (%d %s)", ok, tt.ok, rec.Code, rec.Body)
+			}
+			if tt.ok {
+				return
+			}
+			if rec.Code != tt.status || decodeError(t, rec).Code != tt.code {
+				t.Errorf("answered %d %s, want %d %s", rec.Code, rec.Body, tt.status, tt.code)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"gofamtree/config"
 	"gofamtree/routes"
+	"gofamtree/utils"
 	"log"
 	"net/http"
 	"os"
@@ -13,6 +14,18 @@ func main() {
 	
 	// Initialize database connection
 	config.InitDB()
+	config.InitAuth()
+	config.InitPassword()
+	config.InitGender()
+	config.InitMail()
+	config.InitCORS()
+	config.InitOIDC()
+	config.InitEncryption()
+	config.InitRateLimit()
+	utils.InitMailer()
+	utils.InitPasswordHasher()
+	utils.InitOIDCClient()
+	utils.InitFieldEncryption()
 	defer func() {
 		if sqlDB, err := config.DB.DB(); err == nil {
 			sqlDB.Close()
@@ -31,16 +44,52 @@ func main() {
 	log.Printf("Server starting on port %s", port)
 	log.Printf("API Endpoints:")
 	log.Printf("  POST /admin/login - Admin login")
+	log.Printf("  POST /admin/login/2fa - Second login step when 2FA is enabled")
 	log.Printf("  POST /admin/register - Admin registration")
+	log.Printf("  POST /admin/token/refresh - Exchange a refresh token for new tokens")
+	log.Printf("  GET /admin/oidc/login - Sign in with the configured OpenID provider")
+	log.Printf("  GET /admin/oidc/callback - OpenID provider redirect target")
+	log.Printf("  POST /admin/password/forgot - Email a password reset token")
+	log.Printf("  POST /admin/password/reset - Reset password with a reset token")
+	log.Printf("  GET /shared/{token}/family-tree|persons - Read a house through a share link")
+	log.Printf("All routes below require an Authorization: Bearer <token> header")
+	log.Printf("  POST /admin/password - Change password")
+	log.Printf("  POST /admin/logout - End the current session")
+	log.Printf("  GET|DELETE /admin/sessions - List sessions | Revoke all other sessions")
+	log.Printf("  DELETE /admin/sessions/{id} - Revoke session")
+	log.Printf("  POST /admin/2fa/setup|enable|disable|recovery-codes - Manage two-factor authentication")
+	log.Printf("  GET /admin/admins - List admins (superadmin)")
+	log.Printf("  PUT|DELETE /admin/admins/{id} - Rename | Delete admin")
+	log.Printf("  POST /admin/admins/{id}/disable|enable - Disable | Re-enable admin (superadmin)")
+	log.Printf("  POST /admin/admins/{id}/unlock - Unlock a locked admin account")
+	log.Printf("  GET|POST /admin/invites - List | Create registration invites (superadmin)")
+	log.Printf("  DELETE /admin/invites/{id} - Revoke registration invite")
+	log.Printf("  GET|POST /admin/tokens - List API tokens | Create API token")
+	log.Printf("  DELETE /admin/tokens/{id} - Revoke API token")
 	log.Printf("  GET|POST /houses - List houses | Create house")
 	log.Printf("  GET|PUT|DELETE /houses/{id} - Get|Update|Delete house")
+	log.Printf("  GET|POST /houses/{id}/members - List members | Invite member")
+	log.Printf("  PUT|DELETE /houses/{id}/members/{admin_id} - Change role | Remove member")
+	log.Printf("  GET|POST /houses/{id}/share-links - List | Create share links (owner)")
+	log.Printf("  DELETE /houses/{id}/share-links/{link_id} - Revoke share link (owner)")
+	log.Printf("  GET /invitations - List pending invitations")
+	log.Printf("  POST /invitations/{id}/accept - Accept invitation")
+	log.Printf("  DELETE /invitations/{id} - Decline invitation")
 	log.Printf("  GET|POST /persons - List persons | Create person")
 	log.Printf("  GET|PUT|DELETE /persons/{id} - Get|Update|Delete person")
+	log.Printf("  GET|POST /persons/{id}/names - List | Add names of a person")
+	log.Printf("  PUT|DELETE /persons/{id}/names/{name_id} - Update | Delete name")
 	log.Printf("  GET|POST /relations - List relations | Create relation")
 	log.Printf("  GET|PUT|DELETE /relations/{id} - Get|Update|Delete relation")
+	log.Printf("  GET|POST /events - List events | Create event")
+	log.Printf("  GET|PUT|DELETE /events/{id} - Get|Update|Delete event")
+	log.Printf("  GET|POST /places - Search places | Create place")
+	log.Printf("  GET|PUT|DELETE /places/{id} - Get|Update|Delete place")
+	log.Printf("  POST /places/{id}/merge - Merge a duplicate place into this one")
 	log.Printf("  GET /family-tree/{house_id} - Get family tree for house")
+	log.Printf("  GET /audit-log - Query the audit log")
 
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
+	if err := http.ListenAndServe(":"+port, routes.Handler()); err != nil {
 		log.Fatal("Server failed to start:", err)
 	}
 }
@@ -0,0 +1,22 @@
+-- Adds house membership with owner/editor/viewer roles
+-- Run against an existing gofamtree_new database:
+-- psql -d gofamtree_new -f migrations/001_house_members.sql
+
+CREATE TABLE IF NOT EXISTS house_members (
+    id SERIAL PRIMARY KEY,
+    house_id INTEGER NOT NULL REFERENCES houses(id) ON DELETE CASCADE,
+    admin_id INTEGER NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'active')),
+    invited_by INTEGER REFERENCES admins(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    accepted_at TIMESTAMP,
+    UNIQUE(house_id, admin_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_house_members_admin_id ON house_members(admin_id);
+
+-- Every existing house creator becomes the owner of their house
+INSERT INTO house_members (house_id, admin_id, role, status, created_at, accepted_at)
+SELECT id, created_by, 'owner', 'active', NOW(), NOW() FROM houses
+ON CONFLICT (house_id, admin_id) DO NOTHING;
@@ -0,0 +1,15 @@
+-- Adds admin email addresses and password reset tokens
+-- psql -d gofamtree_new -f migrations/003_password_resets.sql
+
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS email TEXT UNIQUE;
+
+CREATE TABLE IF NOT EXISTS password_resets (
+    id SERIAL PRIMARY KEY,
+    admin_id INTEGER NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    token_hash TEXT UNIQUE NOT NULL, -- SHA-256 of the emailed token
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_resets_admin_id ON password_resets(admin_id);
@@ -0,0 +1,4 @@
+-- Adds account lockout after repeated failed logins
+-- psql -d gofamtree_new -f migrations/004_login_lockout.sql
+
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP;
@@ -0,0 +1,16 @@
+-- Adds TOTP two-factor authentication and recovery codes
+-- psql -d gofamtree_new -f migrations/005_totp.sql
+
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS totp_secret TEXT;
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS totp_last_counter BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id SERIAL PRIMARY KEY,
+    admin_id INTEGER NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL, -- SHA-256 of the normalized code
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_admin_id ON recovery_codes(admin_id);
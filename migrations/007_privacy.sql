@@ -0,0 +1,9 @@
+-- Adds living-person privacy settings
+-- psql -d gofamtree_new -f migrations/007_privacy.sql
+
+ALTER TABLE houses ADD COLUMN IF NOT EXISTS privacy_min_role TEXT NOT NULL DEFAULT 'editor'
+    CHECK (privacy_min_role IN ('owner', 'editor', 'viewer'));
+ALTER TABLE houses ADD COLUMN IF NOT EXISTS privacy_living_years INTEGER NOT NULL DEFAULT 100
+    CHECK (privacy_living_years > 0);
+
+ALTER TABLE persons ADD COLUMN IF NOT EXISTS is_living BOOLEAN;
@@ -0,0 +1,21 @@
+-- Adds superadmins, disabled accounts and registration invites
+-- psql -d gofamtree_new -f migrations/008_admin_management.sql
+
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS is_superadmin BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMP;
+
+-- Registration is now closed; the oldest existing admin becomes the superadmin
+UPDATE admins SET is_superadmin = TRUE
+WHERE id = (SELECT MIN(id) FROM admins)
+  AND NOT EXISTS (SELECT 1 FROM admins WHERE is_superadmin);
+
+CREATE TABLE IF NOT EXISTS registration_invites (
+    id SERIAL PRIMARY KEY,
+    token_hash TEXT UNIQUE NOT NULL, -- SHA-256 of the invite token
+    email TEXT, -- if set, the new account must use this address
+    created_by INTEGER REFERENCES admins(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    used_by INTEGER REFERENCES admins(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
@@ -0,0 +1,15 @@
+-- Adds identities for OpenID Connect single sign-on
+-- psql -d gofamtree_new -f migrations/009_oidc.sql
+
+CREATE TABLE IF NOT EXISTS admin_identities (
+    id SERIAL PRIMARY KEY,
+    admin_id INTEGER NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    issuer TEXT NOT NULL,
+    subject TEXT NOT NULL, -- the provider's "sub" claim
+    email TEXT,
+    last_login_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(issuer, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_identities_admin_id ON admin_identities(admin_id);
@@ -0,0 +1,26 @@
+-- Adds login sessions and rotating refresh tokens
+-- psql -d gofamtree_new -f migrations/010_sessions.sql
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id SERIAL PRIMARY KEY,
+    admin_id INTEGER NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    device TEXT, -- short description derived from the user agent
+    user_agent TEXT,
+    ip_address TEXT, -- client IP of the latest login or refresh
+    expires_at TIMESTAMP NOT NULL, -- absolute limit, refreshing cannot extend past it
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    session_id INTEGER NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    token_hash TEXT UNIQUE NOT NULL, -- SHA-256 of the refresh token
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP, -- set when rotated; presenting it again revokes the session
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_admin_id ON sessions(admin_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session_id ON refresh_tokens(session_id);
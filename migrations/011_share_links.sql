@@ -0,0 +1,18 @@
+-- Adds read-only public share links for houses
+-- psql -d gofamtree_new -f migrations/011_share_links.sql
+
+CREATE TABLE IF NOT EXISTS share_links (
+    id SERIAL PRIMARY KEY,
+    house_id INTEGER NOT NULL REFERENCES houses(id) ON DELETE CASCADE,
+    name TEXT, -- label shown to owners
+    token_hash TEXT UNIQUE NOT NULL, -- SHA-256 of the link token
+    password_hash TEXT, -- optional password, hashed like admin passwords
+    expires_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    use_count INTEGER NOT NULL DEFAULT 0,
+    last_used_at TIMESTAMP,
+    created_by INTEGER REFERENCES admins(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_share_links_house_id ON share_links(house_id);
@@ -0,0 +1,5 @@
+-- Adds date and cause of death to persons
+-- psql -d gofamtree_new -f migrations/012_death_dates.sql
+
+ALTER TABLE persons ADD COLUMN IF NOT EXISTS dod DATE;
+ALTER TABLE persons ADD COLUMN IF NOT EXISTS cause_of_death TEXT; -- encrypted when ENCRYPTION_KEYS is set
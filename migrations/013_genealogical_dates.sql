@@ -0,0 +1,13 @@
+-- Stores birth and death dates as entered, so they can be partial or approximate
+-- ('March 1925', 'about 1890', 'between 1850 and 1855'), with a sort date beside each
+-- psql -d gofamtree_new -f migrations/013_genealogical_dates.sql
+
+ALTER TABLE persons ADD COLUMN IF NOT EXISTS dob_sort DATE;
+ALTER TABLE persons ADD COLUMN IF NOT EXISTS dod_sort DATE;
+
+-- Existing dates are exact, so they sort as themselves
+UPDATE persons SET dob_sort = dob::text::date WHERE dob_sort IS NULL AND dob IS NOT NULL AND dob::text ~ '^[0-9]{4}-[0-9]{2}-[0-9]{2}$';
+UPDATE persons SET dod_sort = dod::text::date WHERE dod_sort IS NULL AND dod IS NOT NULL AND dod::text ~ '^[0-9]{4}-[0-9]{2}-[0-9]{2}$';
+
+ALTER TABLE persons ALTER COLUMN dob TYPE TEXT;
+ALTER TABLE persons ALTER COLUMN dod TYPE TEXT;
@@ -0,0 +1,25 @@
+-- Adds life events (birth, baptism, marriage, ...) and the persons taking part in them
+-- psql -d gofamtree_new -f migrations/014_events.sql
+
+CREATE TABLE IF NOT EXISTS events (
+    id SERIAL PRIMARY KEY,
+    house_id INTEGER NOT NULL REFERENCES houses(id) ON DELETE CASCADE,
+    event_type TEXT NOT NULL CHECK (event_type IN ('birth', 'baptism', 'marriage', 'divorce', 'migration', 'residence', 'occupation', 'death', 'burial')),
+    date TEXT, -- genealogical date as entered, e.g. 'about 1890'
+    date_sort DATE, -- first day date can stand for, for ordering
+    place TEXT,
+    description TEXT,
+    relation_id INTEGER REFERENCES relations(id) ON DELETE SET NULL, -- spouse relation of a marriage or divorce
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS event_participants (
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    person_id INTEGER NOT NULL REFERENCES persons(id) ON DELETE CASCADE,
+    role TEXT, -- e.g. principal, spouse, witness, godparent
+    PRIMARY KEY (event_id, person_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_events_house_id ON events(house_id);
+CREATE INDEX IF NOT EXISTS idx_events_relation_id ON events(relation_id);
+CREATE INDEX IF NOT EXISTS idx_event_participants_person_id ON event_participants(person_id);
@@ -0,0 +1,36 @@
+-- Adds places with a village -> district -> province -> country hierarchy and
+-- links them to persons and events
+-- psql -d gofamtree_new -f migrations/015_places.sql
+
+CREATE TABLE IF NOT EXISTS places (
+    id SERIAL PRIMARY KEY,
+    house_id INTEGER NOT NULL REFERENCES houses(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    place_type TEXT NOT NULL CHECK (place_type IN ('village', 'district', 'province', 'country')),
+    parent_id INTEGER REFERENCES places(id) ON DELETE SET NULL, -- enclosing place
+    latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90),
+    longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180),
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Alternate and historical names, e.g. Batavia for Jakarta
+CREATE TABLE IF NOT EXISTS place_names (
+    id SERIAL PRIMARY KEY,
+    place_id INTEGER NOT NULL REFERENCES places(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('alternate', 'historical')),
+    language TEXT,
+    from_date TEXT, -- genealogical dates as entered
+    to_date TEXT
+);
+
+ALTER TABLE persons ADD COLUMN IF NOT EXISTS birth_place_id INTEGER REFERENCES places(id) ON DELETE SET NULL;
+ALTER TABLE persons ADD COLUMN IF NOT EXISTS death_place_id INTEGER REFERENCES places(id) ON DELETE SET NULL;
+ALTER TABLE events ADD COLUMN IF NOT EXISTS place_id INTEGER REFERENCES places(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_places_house_id ON places(house_id);
+CREATE INDEX IF NOT EXISTS idx_places_parent_id ON places(parent_id);
+CREATE INDEX IF NOT EXISTS idx_place_names_place_id ON place_names(place_id);
+CREATE INDEX IF NOT EXISTS idx_persons_birth_place_id ON persons(birth_place_id);
+CREATE INDEX IF NOT EXISTS idx_persons_death_place_id ON persons(death_place_id);
+CREATE INDEX IF NOT EXISTS idx_events_place_id ON events(place_id);
@@ -0,0 +1,19 @@
+-- Adds person names: birth, married, nickname and alias, in any language or script
+-- psql -d gofamtree_new -f migrations/016_person_names.sql
+
+CREATE TABLE IF NOT EXISTS person_names (
+    id SERIAL PRIMARY KEY,
+    person_id INTEGER NOT NULL REFERENCES persons(id) ON DELETE CASCADE,
+    name_type TEXT NOT NULL CHECK (name_type IN ('birth', 'married', 'nickname', 'alias')),
+    prefix TEXT,
+    given_name TEXT,
+    surname TEXT,
+    suffix TEXT,
+    language TEXT, -- language or script, e.g. 'zh-Hant'
+    preferred BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_person_names_person_id ON person_names(person_id);
+-- At most one preferred name per person
+CREATE UNIQUE INDEX IF NOT EXISTS idx_person_names_preferred ON person_names(person_id) WHERE preferred;
@@ -0,0 +1,12 @@
+-- Opens persons.gender to the configurable GENDERS vocabulary, which always
+-- includes unknown and other; the API checks values against the vocabulary
+-- psql -d gofamtree_new -f migrations/017_gender_vocabulary.sql
+
+ALTER TABLE persons DROP CONSTRAINT IF EXISTS persons_gender_check;
+
+-- Persons recorded without a gender
+UPDATE persons SET gender = 'unknown' WHERE gender IS NULL OR gender = '';
+
+ALTER TABLE persons ALTER COLUMN gender SET DEFAULT 'unknown';
+ALTER TABLE persons ALTER COLUMN gender SET NOT NULL;
+ALTER TABLE persons ADD CONSTRAINT persons_gender_check CHECK (gender ~ '^[a-z][a-z0-9_-]*$');
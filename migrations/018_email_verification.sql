@@ -0,0 +1,5 @@
+-- Records when an admin's email was shown to reach them; single sign-on only
+-- links identities to verified addresses
+-- psql -d gofamtree_new -f migrations/018_email_verification.sql
+
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP;
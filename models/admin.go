@@ -5,8 +5,29 @@ import (
 )
 
 type Admin struct {
-	ID        uint      `json:"id" gorm:"primaryKey;column:id"`
-	Username  string    `json:"username" gorm:"unique;not null;column:username"`
-	Password  string    `json:"-" gorm:"not null;column:password"` // Hashed, hidden from JSON
-	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+	ID              uint       `json:"id" gorm:"primaryKey;column:id"`
+	Username        string     `json:"username" gorm:"unique;not null;column:username"`
+	Password        string     `json:"-" gorm:"not null;column:password"` // Hashed, hidden from JSON
+	Email           *string    `json:"email,omitempty" gorm:"unique;column:email"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty" gorm:"column:email_verified_at"`      // Set once mail to the address reached the admin
+	LockedUntil     *time.Time `json:"locked_until,omitempty" gorm:"column:locked_until"`                // Set after repeated failed logins
+	IsSuperadmin    bool       `json:"is_superadmin" gorm:"not null;default:false;column:is_superadmin"` // May manage admin accounts and registration invites
+	DisabledAt      *time.Time `json:"disabled_at,omitempty" gorm:"column:disabled_at"`                  // Disabled admins cannot log in
+	CreatedAt       time.Time  `json:"created_at" gorm:"column:created_at"`
+
+	// TOTP two-factor authentication
+	TOTPSecret      string `json:"-" gorm:"column:totp_secret"`
+	TOTPEnabled     bool   `json:"totp_enabled" gorm:"not null;default:false;column:totp_enabled"`
+	TOTPLastCounter int64  `json:"-" gorm:"not null;default:0;column:totp_last_counter"` // Last accepted time step, to block replays
+}
+
+// AdminProfile is the part of an admin shown to the other members of a house
+type AdminProfile struct {
+	ID       uint   `json:"id" gorm:"primaryKey;column:id"`
+	Username string `json:"username" gorm:"column:username"`
+}
+
+// TableName explicitly sets the table name for GORM
+func (AdminProfile) TableName() string {
+	return "admins"
 }
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// AdminIdentity links an admin to an account at an external OpenID provider
+type AdminIdentity struct {
+	ID          uint       `json:"id" gorm:"primaryKey;column:id"`
+	AdminID     uint       `json:"admin_id" gorm:"not null;column:admin_id"`
+	Issuer      string     `json:"issuer" gorm:"not null;uniqueIndex:idx_admin_identities_issuer_subject;column:issuer"`
+	Subject     string     `json:"subject" gorm:"not null;uniqueIndex:idx_admin_identities_issuer_subject;column:subject"`
+	Email       string     `json:"email,omitempty" gorm:"column:email"`
+	LastLoginAt *time.Time `json:"last_login_at" gorm:"column:last_login_at"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"column:created_at"`
+}
+
+// TableName explicitly sets the table name for GORM
+func (AdminIdentity) TableName() string {
+	return "admin_identities"
+}
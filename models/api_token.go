@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// API token scopes
+const (
+	TokenScopeRead      = "read"
+	TokenScopeReadWrite = "read-write"
+)
+
+type APIToken struct {
+	ID         uint       `json:"id" gorm:"primaryKey;column:id"`
+	AdminID    uint       `json:"admin_id" gorm:"not null;column:admin_id"`
+	Name       string     `json:"name" gorm:"not null;column:name"`
+	TokenHash  string     `json:"-" gorm:"not null;column:token_hash"` // Hashed, hidden from JSON
+	Scope      string     `json:"scope" gorm:"type:text;not null;column:scope"`
+	ExpiresAt  *time.Time `json:"expires_at" gorm:"column:expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at" gorm:"column:last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at" gorm:"column:revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"column:created_at"`
+}
+
+// TableName explicitly sets the table name for GORM
+func (APIToken) TableName() string {
+	return "api_tokens"
+}
+
+// Active reports whether the token is neither revoked nor expired
+func (t APIToken) Active(now time.Time) bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || now.Before(*t.ExpiresAt))
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audit actions
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// AuditLog records one create, update or delete. Rows deliberately have no foreign
+// keys so they survive the deletion of the house, entity or admin they describe.
+type AuditLog struct {
+	ID            uint      `json:"id" gorm:"primaryKey;column:id"`
+	HouseID       *uint     `json:"house_id" gorm:"column:house_id"`
+	ActorID       uint      `json:"actor_id" gorm:"not null;column:actor_id"`
+	ActorUsername string    `json:"actor_username" gorm:"column:actor_username"`
+	APITokenID    *uint     `json:"api_token_id,omitempty" gorm:"column:api_token_id"`
+	Action        string    `json:"action" gorm:"type:text;not null;column:action"`
+	EntityType    string    `json:"entity_type" gorm:"type:text;not null;column:entity_type"`
+	EntityID      uint      `json:"entity_id" gorm:"not null;column:entity_id"`
+	Before        JSON      `json:"before" gorm:"type:jsonb;column:before"`
+	After         JSON      `json:"after" gorm:"type:jsonb;column:after"`
+	ClientIP      string    `json:"client_ip" gorm:"column:client_ip"`
+	CreatedAt     time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+// TableName explicitly sets the table name for GORM
+func (AuditLog) TableName() string {
+	return "audit_log"
+}
+
+// AuditEncryptedKeys are snapshot fields encrypted at rest like the person
+// columns they copy
+var AuditEncryptedKeys = []string{"contact", "description", "cause_of_death"}
+
+// TransformSnapshot applies transform (encryption or decryption) to the string
+// values of AuditEncryptedKeys in a snapshot
+func TransformSnapshot(snapshot JSON, transform func(value, column string) (string, error)) (JSON, error) {
+	var fields map[string]json.RawMessage
+	if len(snapshot) == 0 || json.Unmarshal(snapshot, &fields) != nil {
+		return snapshot, nil
+	}
+
+	for _, key := range AuditEncryptedKeys {
+		var value *string
+		if raw, ok := fields[key]; !ok || json.Unmarshal(raw, &value) != nil || value == nil {
+			continue
+		}
+		transformed, err := transform(*value, "audit_log."+key)
+		if err != nil {
+			return nil, err
+		}
+		if fields[key], err = json.Marshal(transformed); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(fields)
+}
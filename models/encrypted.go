@@ -0,0 +1,45 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"gofamtree/utils"
+	"reflect"
+
+	"gorm.io/gorm/schema"
+)
+
+func init() {
+	schema.RegisterSerializer("encrypted", EncryptedSerializer{})
+}
+
+// EncryptedSerializer stores a string field AES-GCM encrypted. Tag a field with
+// `gorm:"serializer:encrypted"`; in Go it stays a plain string.
+type EncryptedSerializer struct{}
+
+func (EncryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var stored string
+	switch v := dbValue.(type) {
+	case nil:
+	case []byte:
+		stored = string(v)
+	case string:
+		stored = v
+	default:
+		return fmt.Errorf("cannot scan %T into encrypted field %s", dbValue, field.DBName)
+	}
+
+	plaintext, err := utils.DecryptField(stored, field.DBName)
+	if err != nil {
+		return fmt.Errorf("decrypting %s: %w", field.DBName, err)
+	}
+	return field.Set(ctx, dst, plaintext)
+}
+
+func (EncryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	plaintext, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("encrypted field %s must be a string, got %T", field.DBName, fieldValue)
+	}
+	return utils.EncryptField(plaintext, field.DBName)
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Event types
+const (
+	EventBirth      = "birth"
+	EventBaptism    = "baptism"
+	EventMarriage   = "marriage"
+	EventDivorce    = "divorce"
+	EventMigration  = "migration"
+	EventResidence  = "residence"
+	EventOccupation = "occupation"
+	EventDeath      = "death"
+	EventBurial     = "burial"
+)
+
+// EventTypes lists the event types in the order of a typical life
+var EventTypes = []string{
+	EventBirth, EventBaptism, EventMarriage, EventDivorce, EventMigration,
+	EventResidence, EventOccupation, EventDeath, EventBurial,
+}
+
+// CoupleEvent reports whether events of a type may point to a spouse relation
+func CoupleEvent(eventType string) bool {
+	return eventType == EventMarriage || eventType == EventDivorce
+}
+
+// Event is something that happened to one or more persons of a house
+type Event struct {
+	ID          uint       `json:"id" gorm:"primaryKey;column:id"`
+	HouseID     uint       `json:"house_id" gorm:"not null;column:house_id"`
+	EventType   string     `json:"event_type" gorm:"type:text;not null;column:event_type"`
+	Date        *GenDate   `json:"date" gorm:"type:text;column:date"`
+	DateSort    *time.Time `json:"-" gorm:"type:date;column:date_sort"` // Set by BeforeSave
+	Place       string     `json:"place" gorm:"column:place"`           // as entered
+	PlaceID     *uint      `json:"place_id" gorm:"column:place_id"`     // the place in the places table
+	Description string     `json:"description" gorm:"column:description"`
+	RelationID  *uint      `json:"relation_id" gorm:"column:relation_id"` // spouse relation of a marriage or divorce
+	CreatedAt   time.Time  `json:"created_at" gorm:"column:created_at"`
+
+	// Set when the date, places and description were withheld from the caller
+	Redacted bool `json:"redacted,omitempty" gorm:"-"`
+
+	// Relationships
+	House        *House             `json:"house,omitempty" gorm:"foreignKey:HouseID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Location     *Place             `json:"location,omitempty" gorm:"foreignKey:PlaceID;references:ID;constraint:OnDelete:SET NULL;"`
+	Participants []EventParticipant `json:"participants" gorm:"foreignKey:EventID;references:ID;constraint:OnDelete:CASCADE;"`
+}
+
+// TableName explicitly sets the table name for GORM
+func (Event) TableName() string {
+	return "events"
+}
+
+// BeforeSave keeps the sort column in step with the date
+func (e *Event) BeforeSave(tx *gorm.DB) error {
+	e.DateSort = e.Date.SortKey()
+	return nil
+}
+
+// Redact clears the fields that are private while a participant is alive
+func (e *Event) Redact() {
+	e.Date = nil
+	e.Place = ""
+	e.PlaceID, e.Location = nil, nil
+	e.Description = ""
+	e.Redacted = true
+}
+
+// EventParticipant links a person to an event, with their part in it
+type EventParticipant struct {
+	EventID  uint   `json:"-" gorm:"primaryKey;column:event_id"`
+	PersonID uint   `json:"person_id" gorm:"primaryKey;column:person_id"`
+	Role     string `json:"role,omitempty" gorm:"column:role"` // e.g. principal, spouse, witness, godparent
+
+	Person *Person `json:"person,omitempty" gorm:"foreignKey:PersonID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// TableName explicitly sets the table name for GORM
+func (EventParticipant) TableName() string {
+	return "event_participants"
+}
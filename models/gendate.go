@@ -0,0 +1,362 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Genealogical date qualifiers
+const (
+	DateExact   = "exact"
+	DateAbout   = "about"
+	DateBefore  = "before"
+	DateAfter   = "after"
+	DateBetween = "between"
+)
+
+var ErrInvalidGenDate = errors.New("must be a date such as 1925-03-15, 15 Mar 1925, 1925, about 1890, before 1900 or between 1850 and 1855")
+
+// dateQualifierWords maps the words accepted in front of a date, including the
+// GEDCOM abbreviations, to a qualifier
+var dateQualifierWords = map[string]string{
+	"about": DateAbout, "abt": DateAbout, "approx": DateAbout, "approximately": DateAbout,
+	"circa": DateAbout, "ca": DateAbout, "c": DateAbout,
+	"est": DateAbout, "estimated": DateAbout, "cal": DateAbout, "calculated": DateAbout,
+	"before": DateBefore, "bef": DateBefore,
+	"after": DateAfter, "aft": DateAfter,
+	"between": DateBetween, "bet": DateBetween, "from": DateBetween,
+}
+
+var monthNames = []string{
+	"january", "february", "march", "april", "may", "june",
+	"july", "august", "september", "october", "november", "december",
+}
+
+var isoDatePattern = regexp.MustCompile(`^(\d{3,4})(?:-(\d{1,2})(?:-(\d{1,2}))?)?$`)
+
+// PartialDate is a calendar date whose month and day may be unknown (zero)
+type PartialDate struct {
+	Year  int `json:"year"`
+	Month int `json:"month,omitempty"`
+	Day   int `json:"day,omitempty"`
+}
+
+// String formats the date as YYYY, YYYY-MM or YYYY-MM-DD
+func (d PartialDate) String() string {
+	switch {
+	case d.Month == 0:
+		return fmt.Sprintf("%04d", d.Year)
+	case d.Day == 0:
+		return fmt.Sprintf("%04d-%02d", d.Year, d.Month)
+	default:
+		return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+	}
+}
+
+// first returns the earliest day the date can stand for
+func (d PartialDate) first() time.Time {
+	month, day := time.Month(max(d.Month, 1)), max(d.Day, 1)
+	return time.Date(d.Year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// last returns the latest day the date can stand for
+func (d PartialDate) last() time.Time {
+	switch {
+	case d.Month == 0:
+		return time.Date(d.Year, time.December, 31, 0, 0, 0, 0, time.UTC)
+	case d.Day == 0:
+		return time.Date(d.Year, time.Month(d.Month)+1, 0, 0, 0, 0, 0, time.UTC)
+	default:
+		return d.first()
+	}
+}
+
+func (d PartialDate) valid() bool {
+	if d.Year < 1 || d.Year > 9999 || d.Month < 0 || d.Month > 12 || d.Day < 0 {
+		return false
+	}
+	if d.Day == 0 {
+		return true
+	}
+	return d.Month != 0 && d.Day <= time.Date(d.Year, time.Month(d.Month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// GenDate is a genealogical date: exact or partial, approximate, open-ended or
+// a range. Original keeps the text as it was entered; the other fields are
+// parsed from it.
+type GenDate struct {
+	Qualifier string      // one of the Date* qualifiers
+	Date      PartialDate // the date, or the start of a range
+	End       PartialDate // end of a range, between only
+	Original  string
+}
+
+// ParseGenDate reads dates such as "1925-03-15", "15 Mar 1925", "March 1925",
+// "1925", "about 1890", "bef. 1900", "after 1850" and "between 1850 and 1855".
+// GEDCOM keywords (ABT, BEF, AFT, BET ... AND, FROM ... TO) are accepted too.
+func ParseGenDate(text string) (GenDate, error) {
+	date := GenDate{Qualifier: DateExact, Original: strings.TrimSpace(text)}
+
+	// Timestamps come from snapshots written while dates were plain DATE columns
+	if parsed, err := time.Parse(time.RFC3339, date.Original); err == nil {
+		date.Date = PartialDate{Year: parsed.Year(), Month: int(parsed.Month()), Day: parsed.Day()}
+		return date, nil
+	}
+
+	rest := strings.ToLower(date.Original)
+	if trimmed, ok := strings.CutPrefix(rest, "~"); ok {
+		date.Qualifier, rest = DateAbout, trimmed
+	} else if word, after, found := strings.Cut(rest, " "); found {
+		if qualifier, ok := dateQualifierWords[strings.TrimSuffix(word, ".")]; ok {
+			date.Qualifier, rest = qualifier, after
+		}
+	}
+
+	var err error
+	if date.Qualifier == DateBetween {
+		start, end, found := strings.Cut(rest, " and ")
+		if !found {
+			start, end, found = strings.Cut(rest, " to ")
+		}
+		if !found {
+			return GenDate{}, ErrInvalidGenDate
+		}
+		if date.Date, err = parsePartialDate(start); err != nil {
+			return GenDate{}, err
+		}
+		if date.End, err = parsePartialDate(end); err != nil {
+			return GenDate{}, err
+		}
+		if date.End.last().Before(date.Date.first()) {
+			return GenDate{}, errors.New("must end after it starts")
+		}
+		return date, nil
+	}
+
+	if date.Date, err = parsePartialDate(rest); err != nil {
+		return GenDate{}, err
+	}
+	return date, nil
+}
+
+// parsePartialDate reads YYYY[-MM[-DD]], "[D] Mon YYYY" or "Mon [D,] YYYY"
+func parsePartialDate(text string) (PartialDate, error) {
+	text = strings.TrimSpace(text)
+	var date PartialDate
+
+	if match := isoDatePattern.FindStringSubmatch(text); match != nil {
+		date.Year, _ = strconv.Atoi(match[1])
+		date.Month, _ = strconv.Atoi(match[2])
+		date.Day, _ = strconv.Atoi(match[3])
+	} else {
+		fields := strings.Fields(strings.ReplaceAll(text, ",", " "))
+		if len(fields) < 2 || len(fields) > 3 {
+			return date, ErrInvalidGenDate
+		}
+		for _, field := range fields {
+			number, err := strconv.Atoi(field)
+			switch {
+			case err != nil && date.Month == 0:
+				if date.Month = parseMonth(field); date.Month == 0 {
+					return date, ErrInvalidGenDate
+				}
+			case err == nil && len(field) <= 2 && date.Day == 0:
+				date.Day = number
+			case err == nil && len(field) >= 3 && date.Year == 0:
+				date.Year = number
+			default:
+				return date, ErrInvalidGenDate
+			}
+		}
+		if date.Month == 0 || date.Year == 0 {
+			return date, ErrInvalidGenDate
+		}
+	}
+
+	if !date.valid() {
+		return date, ErrInvalidGenDate
+	}
+	return date, nil
+}
+
+// parseMonth reads a month name or its abbreviation of three letters or more,
+// returning 0 if it is neither
+func parseMonth(word string) int {
+	word = strings.TrimSuffix(word, ".")
+	if len(word) < 3 {
+		return 0
+	}
+	for i, name := range monthNames {
+		if strings.HasPrefix(name, word) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// String formats the date in the canonical form ParseGenDate reads back
+func (d GenDate) String() string {
+	switch d.Qualifier {
+	case DateAbout, DateBefore, DateAfter:
+		return d.Qualifier + " " + d.Date.String()
+	case DateBetween:
+		return "between " + d.Date.String() + " and " + d.End.String()
+	default:
+		return d.Date.String()
+	}
+}
+
+// Short formats the date compactly for labels such as lifespans
+func (d GenDate) Short() string {
+	year := strconv.Itoa(d.Date.Year)
+	switch d.Qualifier {
+	case DateAbout:
+		return "c. " + year
+	case DateBefore:
+		return "bef. " + year
+	case DateAfter:
+		return "aft. " + year
+	case DateBetween:
+		if d.End.Year != d.Date.Year {
+			return year + "/" + strconv.Itoa(d.End.Year)
+		}
+	}
+	return year
+}
+
+// Precise reports whether the date names one known day
+func (d GenDate) Precise() bool {
+	return d.Qualifier == DateExact && d.Date.Day != 0
+}
+
+// SortDate places the date on the calendar for ordering: the first day it can
+// stand for, the day before a "before" date and the day after an "after" date
+func (d GenDate) SortDate() time.Time {
+	switch d.Qualifier {
+	case DateBefore:
+		return d.Date.first().AddDate(0, 0, -1)
+	case DateAfter:
+		return d.Date.last().AddDate(0, 0, 1)
+	default:
+		return d.Date.first()
+	}
+}
+
+// Compare orders dates by SortDate, then by how late they can be. It returns
+// -1, 0 or +1.
+func (d GenDate) Compare(other GenDate) int {
+	if c := d.SortDate().Compare(other.SortDate()); c != 0 {
+		return c
+	}
+	return d.latest().Compare(other.latest())
+}
+
+func (d GenDate) latest() time.Time {
+	switch d.Qualifier {
+	case DateBefore:
+		return d.SortDate()
+	case DateBetween:
+		return d.End.last()
+	default:
+		return d.Date.last()
+	}
+}
+
+// EndsBefore reports whether every day d can stand for falls before every day
+// other can stand for, as when a death date is checked against a birth date
+func (d GenDate) EndsBefore(other GenDate) bool {
+	if d.Qualifier == DateAfter || other.Qualifier == DateBefore {
+		return false
+	}
+	return d.latest().Before(other.SortDate())
+}
+
+// SortKey is SortDate for a nullable date column
+func (d *GenDate) SortKey() *time.Time {
+	if d == nil {
+		return nil
+	}
+	key := d.SortDate()
+	return &key
+}
+
+// Value stores the text as it was entered
+func (d GenDate) Value() (driver.Value, error) {
+	if d.Original != "" {
+		return d.Original, nil
+	}
+	return d.String(), nil
+}
+
+// Scan parses a stored date. DATE columns not yet migrated to text scan as
+// time.Time.
+func (d *GenDate) Scan(value interface{}) error {
+	var text string
+	switch v := value.(type) {
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	case time.Time:
+		text = v.Format("2006-01-02")
+	default:
+		return fmt.Errorf("cannot scan %T into GenDate", value)
+	}
+	parsed, err := ParseGenDate(text)
+	if err != nil {
+		return fmt.Errorf("stored date %q: %w", text, err)
+	}
+	*d = parsed
+	return nil
+}
+
+type genDateJSON struct {
+	Text      string       `json:"text"` // canonical form
+	Original  string       `json:"original"`
+	Qualifier string       `json:"qualifier"`
+	Date      PartialDate  `json:"date"`
+	End       *PartialDate `json:"end,omitempty"`
+	Sort      string       `json:"sort"` // YYYY-MM-DD, see SortDate
+}
+
+func (d GenDate) MarshalJSON() ([]byte, error) {
+	out := genDateJSON{
+		Text:      d.String(),
+		Original:  d.Original,
+		Qualifier: d.Qualifier,
+		Date:      d.Date,
+		Sort:      d.SortDate().Format("2006-01-02"),
+	}
+	if d.Qualifier == DateBetween {
+		out.End = &d.End
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON accepts the object MarshalJSON writes as well as a plain
+// string, such as the timestamps in older audit log snapshots
+func (d *GenDate) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		var in genDateJSON
+		if err := json.Unmarshal(data, &in); err != nil {
+			return err
+		}
+		text = in.Original
+		if text == "" {
+			text = in.Text
+		}
+	}
+	parsed, err := ParseGenDate(text)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
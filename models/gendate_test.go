@@ -0,0 +1,238 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func mustParseGenDate(t *testing.T, text string) GenDate {
+	t.Helper()
+	date, err := ParseGenDate(text)
+	if err != nil {
+		t.Fatalf("ParseGenDate(%q): %v", text, err)
+	}
+	return date
+}
+
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParseGenDate(t *testing.T) {
+	tests := []struct {
+		text      string
+		qualifier string
+		date      PartialDate
+		end       PartialDate
+		canonical string
+	}{
+		{"1925-03-15", DateExact, PartialDate{1925, 3, 15}, PartialDate{}, "1925-03-15"},
+		{"1925-3", DateExact, PartialDate{1925, 3, 0}, PartialDate{}, "1925-03"},
+		{"1925", DateExact, PartialDate{1925, 0, 0}, PartialDate{}, "1925"},
+		{"  987 ", DateExact, PartialDate{987, 0, 0}, PartialDate{}, "0987"},
+		{"15 Mar 1925", DateExact, PartialDate{1925, 3, 15}, PartialDate{}, "1925-03-15"},
+		{"March 15, 1925", DateExact, PartialDate{1925, 3, 15}, PartialDate{}, "1925-03-15"},
+		{"sept. 1925", DateExact, PartialDate{1925, 9, 0}, PartialDate{}, "1925-09"},
+		{"29 Feb 2000", DateExact, PartialDate{2000, 2, 29}, PartialDate{}, "2000-02-29"},
+		{"abt 1890", DateAbout, PartialDate{1890, 0, 0}, PartialDate{}, "about 1890"},
+		{"ABT 1890", DateAbout, PartialDate{1890, 0, 0}, PartialDate{}, "about 1890"},
+		{"ca. 1890", DateAbout, PartialDate{1890, 0, 0}, PartialDate{}, "about 1890"},
+		{"~1890", DateAbout, PartialDate{1890, 0, 0}, PartialDate{}, "about 1890"},
+		{"bef. 1900", DateBefore, PartialDate{1900, 0, 0}, PartialDate{}, "before 1900"},
+		{"after Jun 1850", DateAfter, PartialDate{1850, 6, 0}, PartialDate{}, "after 1850-06"},
+		{"bet 1850 and 1855", DateBetween, PartialDate{1850, 0, 0}, PartialDate{1855, 0, 0}, "between 1850 and 1855"},
+		{"FROM 1850 TO MAR 1850", DateBetween, PartialDate{1850, 0, 0}, PartialDate{1850, 3, 0}, "between 1850 and 1850-03"},
+		{"1925-03-15T10:30:00Z", DateExact, PartialDate{1925, 3, 15}, PartialDate{}, "1925-03-15"},
+		{"1925-03-15T23:30:00-05:00", DateExact, PartialDate{1925, 3, 15}, PartialDate{}, "1925-03-15"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			date := mustParseGenDate(t, tt.text)
+			if date.Qualifier != tt.qualifier || date.Date != tt.date || date.End != tt.end {
+				t.Errorf("parsed %+v, want %s %v %v", date, tt.qualifier, tt.date, tt.end)
+			}
+			if got := date.String(); got != tt.canonical {
+				t.Errorf("String() = %q, want %q", got, tt.canonical)
+			}
+			if date.Original == "" {
+				t.Error("the text as entered was not kept")
+			}
+			if again := mustParseGenDate(t, date.String()); again.Qualifier != date.Qualifier || again.Date != date.Date || again.End != date.End {
+				t.Errorf("canonical form %q reads back as %+v", date.String(), again)
+			}
+		})
+	}
+}
+
+func TestParseGenDateRejects(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"sometime",
+		"ma 1900",           // ambiguous month abbreviation
+		"Feb 30 1900",       // no such day
+		"29 Feb 1900",       // not a leap year
+		"1900-13",           // no such month
+		"1900-02-30",        // no such day
+		"bet 1855 and 1850", // reversed range
+		"bet 1850-06 and 1850-05",
+		"between 1850",      // range without an end
+		"about",             // qualifier alone
+		"15 1900",           // day without a month
+		"Mar Apr 1900",      // two months
+		"15 Mar 1925 extra", // too many fields
+		"10000",             // year out of range
+	} {
+		if date, err := ParseGenDate(text); err == nil {
+			t.Errorf("ParseGenDate(%q) = %+v, want an error", text, date)
+		}
+	}
+}
+
+func TestParseMonth(t *testing.T) {
+	tests := map[string]int{
+		"january": 1, "jan": 1, "jan.": 1, "feb": 2, "mar": 3, "may": 5, "jun": 6, "jul": 7,
+		"sep": 9, "sept": 9, "dec": 12,
+		"ma": 0, "ju": 0, "j": 0, "": 0, "smarch": 0, "januaryy": 0,
+	}
+	for word, want := range tests {
+		if got := parseMonth(word); got != want {
+			t.Errorf("parseMonth(%q) = %d, want %d", word, got, want)
+		}
+	}
+}
+
+func TestGenDateSortDate(t *testing.T) {
+	tests := []struct {
+		text string
+		want time.Time
+	}{
+		{"1925-03-15", day(1925, time.March, 15)},
+		{"1925-03", day(1925, time.March, 1)},
+		{"1925", day(1925, time.January, 1)},
+		{"about 1925", day(1925, time.January, 1)},
+		{"before 1925", day(1924, time.December, 31)},
+		{"after 1925", day(1926, time.January, 1)},
+		{"after Feb 2000", day(2000, time.March, 1)},
+		{"between 1850 and 1855", day(1850, time.January, 1)},
+	}
+	for _, tt := range tests {
+		if got := mustParseGenDate(t, tt.text).SortDate(); !got.Equal(tt.want) {
+			t.Errorf("SortDate(%q) = %s, want %s", tt.text, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestGenDateCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1900", "1901", -1},
+		{"1901", "1900", 1},
+		{"1900-01-01", "1900", -1}, // the same first day, but the year can be later
+		{"1900", "about 1900", 0},
+		{"before 1900", "1900", -1},
+		{"after 1900", "1900-12-31", 1},
+		{"between 1900 and 1905", "1900", 1},
+		{"Mar 1900", "1900-03", 0},
+	}
+	for _, tt := range tests {
+		if got := mustParseGenDate(t, tt.a).Compare(mustParseGenDate(t, tt.b)); got != tt.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestGenDateEndsBefore(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"1900", "1901", true},
+		{"1900", "1900", false},
+		{"1900-05", "1900-06-01", true},
+		{"1900", "1900-06-01", false}, // the year may still reach June
+		{"between 1850 and 1855", "1856", true},
+		{"between 1850 and 1855", "1855-06", false},
+		{"before 1900", "1900", true},
+		{"1900", "after 1899", false},  // the later date is open-ended at its start
+		{"after 1850", "1900", false},  // open-ended at its end
+		{"1850", "before 1900", false}, // may lie before anything
+	}
+	for _, tt := range tests {
+		if got := mustParseGenDate(t, tt.a).EndsBefore(mustParseGenDate(t, tt.b)); got != tt.want {
+			t.Errorf("EndsBefore(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestGenDateScan(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{"abt 1890", "about 1890"},
+		{[]byte("bet 1850 and 1855"), "between 1850 and 1855"},
+		{time.Date(1925, time.March, 15, 0, 0, 0, 0, time.UTC), "1925-03-15"},
+	}
+	for _, tt := range tests {
+		var date GenDate
+		if err := date.Scan(tt.value); err != nil || date.String() != tt.want {
+			t.Errorf("Scan(%v) = %q, %v; want %q", tt.value, date.String(), err, tt.want)
+		}
+	}
+
+	var date GenDate
+	if err := date.Scan(42); err == nil {
+		t.Error("Scan accepted an integer")
+	}
+	if err := date.Scan("Feb 30 1900"); err == nil {
+		t.Error("Scan accepted an invalid stored date")
+	}
+}
+
+func TestGenDateValue(t *testing.T) {
+	value, err := mustParseGenDate(t, "abt 1890").Value()
+	if err != nil || value != "abt 1890" {
+		t.Errorf("Value() = %v, %v; want the text as entered", value, err)
+	}
+	value, err = GenDate{Qualifier: DateBefore, Date: PartialDate{Year: 1900}}.Value()
+	if err != nil || value != "before 1900" {
+		t.Errorf("Value() without Original = %v, %v; want the canonical form", value, err)
+	}
+}
+
+func TestGenDateJSON(t *testing.T) {
+	data, err := json.Marshal(mustParseGenDate(t, "bet 1850 and 1855"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"text":"between 1850 and 1855","original":"bet 1850 and 1855","qualifier":"between","date":{"year":1850},"end":{"year":1855},"sort":"1850-01-01"}`
+	if string(data) != want {
+		t.Errorf("MarshalJSON = %s, want %s", data, want)
+	}
+
+	tests := []struct {
+		json string
+		want string
+	}{
+		{string(data), "between 1850 and 1855"},
+		{`{"text":"about 1890"}`, "about 1890"},
+		{`"abt 1890"`, "about 1890"},
+		{`"1925-03-15T00:00:00Z"`, "1925-03-15"},
+	}
+	for _, tt := range tests {
+		var date GenDate
+		if err := json.Unmarshal([]byte(tt.json), &date); err != nil || date.String() != tt.want {
+			t.Errorf("UnmarshalJSON(%s) = %q, %v; want %q", tt.json, date.String(), err, tt.want)
+		}
+	}
+
+	for _, bad := range []string{`"Feb 30 1900"`, `42`, `{"text":"ma 1900"}`} {
+		var date GenDate
+		if err := json.Unmarshal([]byte(bad), &date); err == nil {
+			t.Errorf("UnmarshalJSON(%s) accepted an invalid date", bad)
+		}
+	}
+}
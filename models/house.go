@@ -2,13 +2,25 @@ package models
 
 import "time"
 
+// Default privacy policy: viewers see living persons redacted, and anyone born
+// in the last 100 years without an explicit status is presumed living
+const (
+	DefaultPrivacyMinRole     = RoleEditor
+	DefaultPrivacyLivingYears = 100
+)
+
 type House struct {
 	ID        uint      `json:"id" gorm:"primaryKey;column:id"`
 	Name      string    `json:"name" gorm:"not null;column:name"`
 	CreatedBy uint      `json:"created_by" gorm:"not null;column:created_by"`
 	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+
+	// Privacy policy for living persons
+	PrivacyMinRole     string `json:"privacy_min_role" gorm:"type:text;not null;default:editor;column:privacy_min_role"`
+	PrivacyLivingYears int    `json:"privacy_living_years" gorm:"not null;default:100;column:privacy_living_years"`
 	
 	// Relationships
-	Admin   Admin    `json:"admin" gorm:"foreignKey:CreatedBy;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Admin   AdminProfile `json:"admin" gorm:"foreignKey:CreatedBy;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 	Persons []Person `json:"persons,omitempty" gorm:"foreignKey:HouseID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Members []HouseMember `json:"members,omitempty" gorm:"foreignKey:HouseID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 }
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// House roles, from most to least privileged
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// Membership states - an invitation stays pending until the invitee accepts it
+const (
+	MemberStatusPending = "pending"
+	MemberStatusActive  = "active"
+)
+
+type HouseMember struct {
+	ID         uint       `json:"id" gorm:"primaryKey;column:id"`
+	HouseID    uint       `json:"house_id" gorm:"not null;column:house_id"`
+	AdminID    uint       `json:"admin_id" gorm:"not null;column:admin_id"`
+	Role       string     `json:"role" gorm:"type:text;not null;column:role"`
+	Status     string     `json:"status" gorm:"type:text;not null;column:status"`
+	InvitedBy  *uint      `json:"invited_by" gorm:"column:invited_by"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"column:created_at"`
+	AcceptedAt *time.Time `json:"accepted_at" gorm:"column:accepted_at"`
+
+	// Relationships
+	Admin AdminProfile `json:"admin" gorm:"foreignKey:AdminID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	House *House       `json:"house,omitempty" gorm:"foreignKey:HouseID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// TableName explicitly sets the table name for GORM
+func (HouseMember) TableName() string {
+	return "house_members"
+}
+
+// RolesAtLeast lists the roles that include the permissions of minRole
+func RolesAtLeast(minRole string) []string {
+	switch minRole {
+	case RoleOwner:
+		return []string{RoleOwner}
+	case RoleEditor:
+		return []string{RoleOwner, RoleEditor}
+	default:
+		return []string{RoleOwner, RoleEditor, RoleViewer}
+	}
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type PasswordReset struct {
+	ID        uint       `json:"id" gorm:"primaryKey;column:id"`
+	AdminID   uint       `json:"admin_id" gorm:"not null;column:admin_id"`
+	TokenHash string     `json:"-" gorm:"unique;not null;column:token_hash"` // SHA-256 of the emailed token
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null;column:expires_at"`
+	UsedAt    *time.Time `json:"used_at" gorm:"column:used_at"`
+	CreatedAt time.Time  `json:"created_at" gorm:"column:created_at"`
+}
+
+// TableName explicitly sets the table name for GORM
+func (PasswordReset) TableName() string {
+	return "password_resets"
+}
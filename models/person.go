@@ -1,24 +1,197 @@
 package models
 
 import (
+	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type Person struct {
-	ID          uint       `json:"id" gorm:"primaryKey;column:id"`
-	HouseID     uint       `json:"house_id" gorm:"not null;column:house_id"`
-	Name        string     `json:"name" gorm:"not null;column:name"`
-	Contact     string     `json:"contact" gorm:"column:contact"`
-	Description string     `json:"description" gorm:"column:description"`
-	Gender      string     `json:"gender" gorm:"type:text;column:gender"`
-	DOB         *time.Time `json:"dob" gorm:"type:date;column:dob"`
-	CreatedAt   time.Time  `json:"created_at" gorm:"column:created_at"`
-	
+	ID           uint       `json:"id" gorm:"primaryKey;column:id"`
+	HouseID      uint       `json:"house_id" gorm:"not null;column:house_id"`
+	Name         string     `json:"name" gorm:"not null;column:name"`
+	Contact      string     `json:"contact" gorm:"serializer:encrypted;column:contact"`         // Encrypted at rest
+	Description  string     `json:"description" gorm:"serializer:encrypted;column:description"` // Encrypted at rest
+	Gender       string     `json:"gender" gorm:"type:text;not null;column:gender"`
+	DOB          *GenDate   `json:"dob" gorm:"type:text;column:dob"`    // Date of birth, as entered
+	DOBSort      *time.Time `json:"-" gorm:"type:date;column:dob_sort"` // Set by BeforeSave
+	DOD          *GenDate   `json:"dod" gorm:"type:text;column:dod"`    // Date of death, as entered
+	DODSort      *time.Time `json:"-" gorm:"type:date;column:dod_sort"` // Set by BeforeSave
+	BirthPlaceID *uint      `json:"birth_place_id" gorm:"column:birth_place_id"`
+	DeathPlaceID *uint      `json:"death_place_id" gorm:"column:death_place_id"`
+	IsLiving     *bool      `json:"is_living" gorm:"column:is_living"`                                // nil means decide from dates
+	CauseOfDeath string     `json:"cause_of_death" gorm:"serializer:encrypted;column:cause_of_death"` // Encrypted at rest
+	CreatedAt    time.Time  `json:"created_at" gorm:"column:created_at"`
+
+	// Set when contact, description and dates were withheld from the caller
+	Redacted bool `json:"redacted,omitempty" gorm:"-"`
+
+	// Computed for responses by FillLifespan
+	Status     string `json:"status,omitempty" gorm:"-"`       // living/deceased
+	Age        *int   `json:"age,omitempty" gorm:"-"`          // current age of the living
+	AgeAtDeath *int   `json:"age_at_death,omitempty" gorm:"-"` // needs both dates
+	Lifespan   string `json:"lifespan,omitempty" gorm:"-"`     // e.g. 1925-2003, 1950- or c. 1890-?
+
+	// Events the person takes part in, filled by GetPerson
+	Events []Event `json:"events,omitempty" gorm:"-"`
+
+	// Other names, loaded by GetPerson and GetPersons
+	Names []PersonName `json:"names,omitempty" gorm:"foreignKey:PersonID;references:ID;constraint:OnDelete:CASCADE;"`
+
 	// Relationships
-	House House `json:"house" gorm:"foreignKey:HouseID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	House      House  `json:"house" gorm:"foreignKey:HouseID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	BirthPlace *Place `json:"birth_place,omitempty" gorm:"foreignKey:BirthPlaceID;references:ID;constraint:OnDelete:SET NULL;"`
+	DeathPlace *Place `json:"death_place,omitempty" gorm:"foreignKey:DeathPlaceID;references:ID;constraint:OnDelete:SET NULL;"`
 }
 
 // TableName explicitly sets the table name for GORM
 func (Person) TableName() string {
 	return "persons"
 }
+
+// BeforeSave keeps the sort columns in step with the dates, so that SQL can
+// order and filter by them
+func (p *Person) BeforeSave(tx *gorm.DB) error {
+	p.DOBSort = p.DOB.SortKey()
+	p.DODSort = p.DOD.SortKey()
+	return nil
+}
+
+// Kinds of name a person is or was known by
+const (
+	NameBirth    = "birth"
+	NameMarried  = "married"
+	NameNickname = "nickname"
+	NameAlias    = "alias"
+)
+
+// NameTypes lists the kinds of person names
+var NameTypes = []string{NameBirth, NameMarried, NameNickname, NameAlias}
+
+// PersonName is one of the names of a person, such as a birth name, a married
+// surname or a name in another script
+type PersonName struct {
+	ID        uint      `json:"id" gorm:"primaryKey;column:id"`
+	PersonID  uint      `json:"person_id" gorm:"not null;column:person_id"`
+	NameType  string    `json:"name_type" gorm:"type:text;not null;column:name_type"` // birth/married/nickname/alias
+	Prefix    string    `json:"prefix,omitempty" gorm:"column:prefix"`                // e.g. Dr., Sir
+	GivenName string    `json:"given_name" gorm:"column:given_name"`
+	Surname   string    `json:"surname" gorm:"column:surname"`
+	Suffix    string    `json:"suffix,omitempty" gorm:"column:suffix"`     // e.g. Jr., III
+	Language  string    `json:"language,omitempty" gorm:"column:language"` // language or script, e.g. zh-Hant
+	Preferred bool      `json:"preferred" gorm:"not null;column:preferred"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+// TableName explicitly sets the table name for GORM
+func (PersonName) TableName() string {
+	return "person_names"
+}
+
+// FullName joins the parts of the name that are set
+func (n PersonName) FullName() string {
+	parts := make([]string, 0, 4)
+	for _, part := range []string{n.Prefix, n.GivenName, n.Surname, n.Suffix} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// PersonNameSQL is FullName on the person_names table, and PersonNameReversedSQL
+// the surname followed by the given name, as in "Smith John"
+const (
+	PersonNameSQL = `concat_ws(' ', NULLIF(person_names.prefix, ''), NULLIF(person_names.given_name, ''),
+	NULLIF(person_names.surname, ''), NULLIF(person_names.suffix, ''))`
+	PersonNameReversedSQL = `concat_ws(' ', NULLIF(person_names.surname, ''), NULLIF(person_names.given_name, ''))`
+)
+
+// Vital status of a person in responses
+const (
+	StatusLiving   = "living"
+	StatusDeceased = "deceased"
+)
+
+// PresumedLiving reports whether the person should be treated as living. An
+// explicit flag wins and a death date means deceased; otherwise anyone born
+// within livingYears, or with no birth date at all, is presumed alive.
+func (p Person) PresumedLiving(now time.Time, livingYears int) bool {
+	if p.IsLiving != nil {
+		return *p.IsLiving
+	}
+	if p.DOD != nil {
+		return false
+	}
+	if p.DOB != nil {
+		return p.DOB.SortDate().After(now.AddDate(-livingYears, 0, 0))
+	}
+	return true
+}
+
+// PresumedLivingSQL is PresumedLiving as a condition on the persons table, with
+// livingYears taken from each person's house
+const PresumedLivingSQL = `(persons.is_living = TRUE OR (persons.is_living IS NULL AND persons.dod IS NULL AND
+	(persons.dob_sort IS NULL OR persons.dob_sort > CURRENT_DATE - (SELECT houses.privacy_living_years FROM houses WHERE houses.id = persons.house_id) * INTERVAL '1 year')))`
+
+// Redact clears the fields that are private while a person is alive
+func (p *Person) Redact() {
+	p.Contact = ""
+	p.Description = ""
+	p.DOB = nil
+	p.DOD = nil
+	p.BirthPlaceID, p.BirthPlace = nil, nil
+	p.DeathPlaceID, p.DeathPlace = nil, nil
+	p.CauseOfDeath = ""
+	p.Redacted = true
+}
+
+// FillLifespan sets the computed status, ages and lifespan. Call it after
+// Redact, so that withheld dates do not leak through the age. Ages are only
+// given for exact and approximate dates, counted between their sort dates.
+func (p *Person) FillLifespan(now time.Time, livingYears int) {
+	p.Age, p.AgeAtDeath, p.Lifespan = nil, nil, ""
+	living := p.PresumedLiving(now, livingYears)
+
+	p.Status = StatusDeceased
+	if living {
+		p.Status = StatusLiving
+		if pointInTime(p.DOB) {
+			age := yearsBetween(p.DOB.SortDate(), now)
+			p.Age = &age
+		}
+	} else if pointInTime(p.DOB) && pointInTime(p.DOD) {
+		age := yearsBetween(p.DOB.SortDate(), p.DOD.SortDate())
+		p.AgeAtDeath = &age
+	}
+
+	if p.DOB == nil && p.DOD == nil {
+		return
+	}
+	born, died := "?", ""
+	if p.DOB != nil {
+		born = p.DOB.Short()
+	}
+	if p.DOD != nil {
+		died = p.DOD.Short()
+	} else if !living {
+		died = "?"
+	}
+	p.Lifespan = born + "-" + died
+}
+
+// pointInTime reports whether a date is exact or approximate, rather than
+// open-ended or a range
+func pointInTime(date *GenDate) bool {
+	return date != nil && (date.Qualifier == DateExact || date.Qualifier == DateAbout)
+}
+
+// yearsBetween counts the full years from one date to another
+func yearsBetween(from, to time.Time) int {
+	years := to.Year() - from.Year()
+	if to.Month() < from.Month() || (to.Month() == from.Month() && to.Day() < from.Day()) {
+		years--
+	}
+	return years
+}
@@ -0,0 +1,76 @@
+package models
+
+import "time"
+
+// Place types, from smallest to largest
+const (
+	PlaceVillage  = "village"
+	PlaceDistrict = "district"
+	PlaceProvince = "province"
+	PlaceCountry  = "country"
+)
+
+// PlaceTypes lists the place types from smallest to largest
+var PlaceTypes = []string{PlaceVillage, PlaceDistrict, PlaceProvince, PlaceCountry}
+
+// PlaceContains reports whether a place of type parent may contain one of type
+// child, that is whether parent is the larger kind of place
+func PlaceContains(parent, child string) bool {
+	return placeRank(parent) > placeRank(child)
+}
+
+func placeRank(placeType string) int {
+	for i, t := range PlaceTypes {
+		if t == placeType {
+			return i
+		}
+	}
+	return -1
+}
+
+// Kinds of other names a place is or was known by
+const (
+	PlaceNameAlternate  = "alternate"
+	PlaceNameHistorical = "historical"
+)
+
+// Place is a village, district, province or country, within a house. Places
+// form a hierarchy through ParentID.
+type Place struct {
+	ID        uint      `json:"id" gorm:"primaryKey;column:id"`
+	HouseID   uint      `json:"house_id" gorm:"not null;column:house_id"`
+	Name      string    `json:"name" gorm:"not null;column:name"`
+	PlaceType string    `json:"place_type" gorm:"type:text;not null;column:place_type"`
+	ParentID  *uint     `json:"parent_id" gorm:"column:parent_id"`
+	Latitude  *float64  `json:"latitude" gorm:"column:latitude"`
+	Longitude *float64  `json:"longitude" gorm:"column:longitude"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+
+	// Name with its enclosing places, e.g. "Menteng, Jakarta Pusat, DKI Jakarta, Indonesia"
+	Path string `json:"path,omitempty" gorm:"-"`
+
+	// Relationships
+	Names []PlaceName `json:"names" gorm:"foreignKey:PlaceID;references:ID;constraint:OnDelete:CASCADE;"`
+}
+
+// TableName explicitly sets the table name for GORM
+func (Place) TableName() string {
+	return "places"
+}
+
+// PlaceName is an alternate or historical name of a place, such as Batavia for
+// Jakarta
+type PlaceName struct {
+	ID       uint     `json:"id" gorm:"primaryKey;column:id"`
+	PlaceID  uint     `json:"-" gorm:"not null;column:place_id"`
+	Name     string   `json:"name" gorm:"not null;column:name"`
+	Kind     string   `json:"kind" gorm:"type:text;not null;column:kind"` // alternate/historical
+	Language string   `json:"language,omitempty" gorm:"column:language"`
+	From     *GenDate `json:"from,omitempty" gorm:"type:text;column:from_date"` // when the name came into use
+	To       *GenDate `json:"to,omitempty" gorm:"type:text;column:to_date"`     // when it went out of use
+}
+
+// TableName explicitly sets the table name for GORM
+func (PlaceName) TableName() string {
+	return "place_names"
+}
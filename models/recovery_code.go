@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// RecoveryCode is a single-use code that replaces a TOTP code when the authenticator is lost
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey;column:id"`
+	AdminID   uint       `json:"admin_id" gorm:"not null;column:admin_id"`
+	CodeHash  string     `json:"-" gorm:"not null;column:code_hash"` // SHA-256 of the normalized code
+	UsedAt    *time.Time `json:"used_at" gorm:"column:used_at"`
+	CreatedAt time.Time  `json:"created_at" gorm:"column:created_at"`
+}
+
+// TableName explicitly sets the table name for GORM
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// RegistrationInvite lets someone register an admin account once registration
+// is closed. Only the SHA-256 of the invite token is stored.
+type RegistrationInvite struct {
+	ID        uint       `json:"id" gorm:"primaryKey;column:id"`
+	TokenHash string     `json:"-" gorm:"unique;not null;column:token_hash"`
+	Email     *string    `json:"email,omitempty" gorm:"column:email"` // If set, the new account must use this address
+	CreatedBy *uint      `json:"created_by" gorm:"column:created_by"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null;column:expires_at"`
+	UsedAt    *time.Time `json:"used_at" gorm:"column:used_at"`
+	UsedBy    *uint      `json:"used_by,omitempty" gorm:"column:used_by"`
+	CreatedAt time.Time  `json:"created_at" gorm:"column:created_at"`
+}
+
+// TableName explicitly sets the table name for GORM
+func (RegistrationInvite) TableName() string {
+	return "registration_invites"
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Session is one sign-in on one device. Access tokens carry its ID, so revoking
+// the session logs that device out even before its access token expires.
+type Session struct {
+	ID         uint       `json:"id" gorm:"primaryKey;column:id"`
+	AdminID    uint       `json:"admin_id" gorm:"not null;column:admin_id"`
+	Device     string     `json:"device" gorm:"column:device"`
+	UserAgent  string     `json:"user_agent" gorm:"column:user_agent"`
+	IPAddress  string     `json:"ip_address" gorm:"column:ip_address"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null;column:expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at" gorm:"column:last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at" gorm:"column:revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"column:created_at"`
+	Current    bool       `json:"current" gorm:"-"` // set when listing the caller's own sessions
+}
+
+// TableName explicitly sets the table name for GORM
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// Active reports whether the session is neither revoked nor expired
+func (s Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// RefreshToken is a single-use token that renews a session's access token.
+// Every refresh marks the presented token used and issues a successor.
+type RefreshToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey;column:id"`
+	SessionID uint       `json:"session_id" gorm:"not null;column:session_id"`
+	TokenHash string     `json:"-" gorm:"unique;not null;column:token_hash"` // SHA-256 of the refresh token
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null;column:expires_at"`
+	UsedAt    *time.Time `json:"used_at" gorm:"column:used_at"`
+	CreatedAt time.Time  `json:"created_at" gorm:"column:created_at"`
+}
+
+// TableName explicitly sets the table name for GORM
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
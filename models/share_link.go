@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// ShareLink gives anyone holding its token read-only access to one house,
+// with living persons redacted. Only the SHA-256 of the token is stored.
+type ShareLink struct {
+	ID           uint       `json:"id" gorm:"primaryKey;column:id"`
+	HouseID      uint       `json:"house_id" gorm:"not null;column:house_id"`
+	Name         string     `json:"name" gorm:"column:name"`
+	TokenHash    string     `json:"-" gorm:"unique;not null;column:token_hash"`
+	PasswordHash *string    `json:"-" gorm:"column:password_hash"` // Optional, hashed like admin passwords
+	HasPassword  bool       `json:"has_password" gorm:"-"`         // set by handlers, the hash is never returned
+	ExpiresAt    *time.Time `json:"expires_at" gorm:"column:expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at" gorm:"column:revoked_at"`
+	UseCount     int        `json:"use_count" gorm:"not null;default:0;column:use_count"`
+	LastUsedAt   *time.Time `json:"last_used_at" gorm:"column:last_used_at"`
+	CreatedBy    *uint      `json:"created_by" gorm:"column:created_by"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"column:created_at"`
+}
+
+// TableName explicitly sets the table name for GORM
+func (ShareLink) TableName() string {
+	return "share_links"
+}
+
+// Active reports whether the link is neither revoked nor expired
+func (l ShareLink) Active(now time.Time) bool {
+	return l.RevokedAt == nil && (l.ExpiresAt == nil || now.Before(*l.ExpiresAt))
+}
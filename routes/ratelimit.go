@@ -0,0 +1,153 @@
+package routes
+
+import (
+	"gofamtree/config"
+	"gofamtree/handlers"
+	"gofamtree/utils"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimitByMethod classes a route as read or write by its request method
+const rateLimitByMethod = ""
+
+// rateLimitSweepInterval is how often idle buckets are dropped from memory
+const rateLimitSweepInterval = 10 * time.Minute
+
+// rateLimitHeaders are the response headers clients use to pace themselves
+const rateLimitHeaders = "Retry-After, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy"
+
+// tokenBucket holds the tokens left for one client in one route class
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// rateLimiter keeps a token bucket per client and route class
+type rateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+var limiter = &rateLimiter{buckets: make(map[string]*tokenBucket)}
+
+// rateLimitResult describes a bucket after taking a request from it
+type rateLimitResult struct {
+	allowed    bool
+	remaining  int
+	retryAfter time.Duration // until the next request is allowed, when throttled
+	reset      time.Duration // until the bucket is full again
+}
+
+// take removes one token from the bucket for key, refilling it first for the
+// time that has passed
+func (l *rateLimiter) take(key string, limit config.RateLimit, now time.Time) rateLimitResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	capacity := float64(limit.Requests)
+	perSecond := capacity / limit.Period.Seconds()
+
+	if now.Sub(l.lastSweep) > rateLimitSweepInterval {
+		l.sweep(now)
+	}
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: capacity, updated: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(capacity, bucket.tokens+now.Sub(bucket.updated).Seconds()*perSecond)
+	bucket.updated = now
+
+	result := rateLimitResult{allowed: bucket.tokens >= 1}
+	if result.allowed {
+		bucket.tokens--
+	} else {
+		result.retryAfter = time.Duration((1 - bucket.tokens) / perSecond * float64(time.Second))
+	}
+	result.remaining = int(bucket.tokens)
+	result.reset = time.Duration((capacity - bucket.tokens) / perSecond * float64(time.Second))
+	return result
+}
+
+// sweep drops buckets that have not been used for longer than the longest
+// period, since they would be full by now anyway
+func (l *rateLimiter) sweep(now time.Time) {
+	var longest time.Duration
+	for _, limit := range config.RateLimits {
+		if limit.Period > longest {
+			longest = limit.Period
+		}
+	}
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.updated) > longest {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// Rate limit middleware - throttles each client per route class. Authenticated
+// requests are counted per admin, anonymous ones per client IP.
+func rateLimitMiddleware(class string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		routeClass := class
+		if routeClass == rateLimitByMethod {
+			routeClass = config.RateLimitWrite
+			if r.Method == "GET" || r.Method == "HEAD" {
+				routeClass = config.RateLimitRead
+			}
+		}
+
+		client := "ip:" + utils.ClientIP(r)
+		if adminID := utils.AdminIDFromContext(r.Context()); adminID != 0 {
+			client = "admin:" + strconv.FormatUint(uint64(adminID), 10)
+		}
+
+		if allowRequest(w, r, routeClass, client) {
+			next(w, r)
+		}
+	}
+}
+
+// IP rate limit middleware - throttles each client IP before authentication,
+// so requests with a missing or bad token are limited too and cannot make the
+// server check tokens without end
+func ipRateLimitMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if allowRequest(w, r, config.RateLimitIP, "ip:"+utils.ClientIP(r)) {
+			next(w, r)
+		}
+	}
+}
+
+// allowRequest takes a token from the client's bucket in a route class and
+// sets the RateLimit headers. A throttled request is answered with 429.
+func allowRequest(w http.ResponseWriter, r *http.Request, routeClass, client string) bool {
+	limit, limited := config.RateLimits[routeClass]
+	if !limited {
+		return true
+	}
+
+	result := limiter.take(routeClass+"|"+client, limit, time.Now())
+	w.Header().Set("RateLimit-Policy", strconv.Itoa(limit.Requests)+";w="+strconv.Itoa(ceilSeconds(limit.Period)))
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.reset)))
+
+	if !result.allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.retryAfter)))
+		handlers.WriteError(w, r, http.StatusTooManyRequests, handlers.ErrCodeRateLimited, "Too many requests, try again later")
+		return false
+	}
+	return true
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
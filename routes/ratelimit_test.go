@@ -0,0 +1,126 @@
+package routes
+
+import (
+	"gofamtree/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// withRateLimits replaces the configured limits and starts from empty buckets
+func withRateLimits(t *testing.T, limits map[string]config.RateLimit) {
+	t.Helper()
+	previousLimits, previousLimiter := config.RateLimits, limiter
+	t.Cleanup(func() { config.RateLimits, limiter = previousLimits, previousLimiter })
+
+	config.RateLimits = limits
+	limiter = &rateLimiter{buckets: make(map[string]*tokenBucket)}
+}
+
+func TestTokenBucket(t *testing.T) {
+	withRateLimits(t, nil)
+	limit := config.RateLimit{Requests: 3, Period: time.Minute}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		at         time.Duration
+		key        string
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+		reset      time.Duration
+	}{
+		{"burst 1", 0, "a", true, 2, 0, 20 * time.Second},
+		{"burst 2", 0, "a", true, 1, 0, 40 * time.Second},
+		{"burst 3", 0, "a", true, 0, 0, time.Minute},
+		{"empty", 0, "a", false, 0, 20 * time.Second, time.Minute},
+		{"other client", 0, "b", true, 2, 0, 20 * time.Second},
+		{"half refilled", 10 * time.Second, "a", false, 0, 10 * time.Second, 50 * time.Second},
+		{"one token back", 20 * time.Second, "a", true, 0, 0, time.Minute},
+		{"full again", 2 * time.Minute, "a", true, 2, 0, 20 * time.Second},
+	}
+	for _, tt := range tests {
+		result := limiter.take(tt.key, limit, start.Add(tt.at))
+		if result.allowed != tt.allowed || result.remaining != tt.remaining {
+			t.Errorf("%s: allowed %v with %d left, want %v with %d", tt.name, result.allowed, result.remaining, tt.allowed, tt.remaining)
+		}
+		if result.retryAfter.Round(time.Millisecond) != tt.retryAfter || result.reset.Round(time.Millisecond) != tt.reset {
+			t.Errorf("%s: retry after %v, reset %v; want %v, %v", tt.name, result.retryAfter, result.reset, tt.retryAfter, tt.reset)
+		}
+	}
+}
+
+func TestRateLimitMiddlewareHeaders(t *testing.T) {
+	withRateLimits(t, map[string]config.RateLimit{
+		config.RateLimitRead: {Requests: 2, Period: time.Minute},
+	})
+	handler := rateLimitMiddleware(rateLimitByMethod, func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		status     int
+		remaining  string
+		reset      string
+		retryAfter string
+	}{
+		{http.StatusOK, "1", "30", ""},
+		{http.StatusOK, "0", "60", ""},
+		{http.StatusTooManyRequests, "0", "60", "30"},
+	}
+	for i, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest("GET", "/persons", nil))
+
+		if rec.Code != tt.status {
+			t.Errorf("request %d: status %d, want %d", i+1, rec.Code, tt.status)
+		}
+		want := map[string]string{
+			"RateLimit-Policy":    "2;w=60",
+			"RateLimit-Limit":     "2",
+			"RateLimit-Remaining": tt.remaining,
+			"RateLimit-Reset":     tt.reset,
+			"Retry-After":         tt.retryAfter,
+		}
+		for header, value := range want {
+			if got := rec.Header().Get(header); got != value {
+				t.Errorf("request %d: %s = %q, want %q", i+1, header, got, value)
+			}
+		}
+	}
+
+	// Writes have no limit configured here, so they pass without headers
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("POST", "/persons", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "" {
+		t.Errorf("unlimited class answered %d with RateLimit-Limit %q", rec.Code, rec.Header().Get("RateLimit-Limit"))
+	}
+}
+
+func TestIPRateLimitRunsBeforeAuthentication(t *testing.T) {
+	withRateLimits(t, map[string]config.RateLimit{
+		config.RateLimitIP: {Requests: 2, Period: time.Minute},
+	})
+	handler := ipRateLimitMiddleware(authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("an unauthenticated request reached the handler")
+	}))
+
+	for i, want := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		req := httptest.NewRequest("GET", "/persons", nil)
+		req.RemoteAddr = "198.51.100.7:4321"
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		if rec.Code != want {
+			t.Errorf("request %d without a token: status %d, want %d", i+1, rec.Code, want)
+		}
+	}
+
+	// Another client IP has its own bucket
+	req := httptest.NewRequest("GET", "/persons", nil)
+	req.RemoteAddr = "203.0.113.9:4321"
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("request from another IP: status %d, want 401", rec.Code)
+	}
+}
@@ -0,0 +1,45 @@
+package routes
+
+import (
+	"gofamtree/utils"
+	"net/http"
+)
+
+// requestIDHeader carries the request ID in both directions
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds IDs accepted from proxies and clients
+const maxRequestIDLength = 128
+
+// Handler returns the registered routes wrapped in the middlewares that apply to
+// every request, matched route or not
+func Handler() http.Handler {
+	return requestIDMiddleware(http.DefaultServeMux)
+}
+
+// Request ID middleware - tags every request with an ID that is echoed in the
+// X-Request-ID response header and in error bodies. A well-formed ID set by a
+// proxy or client is kept, so one request can be followed across systems.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = utils.NewRequestID()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+		next.ServeHTTP(w, r.WithContext(utils.WithRequestID(r.Context(), requestID)))
+	})
+}
+
+// validRequestID allows IDs that are safe to log and echo in headers
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
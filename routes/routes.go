@@ -1,44 +1,132 @@
 package routes
 
 import (
+	"gofamtree/config"
 	"gofamtree/handlers"
+	"gofamtree/models"
+	"gofamtree/utils"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 func RegisterRoutes() {
 	// Admin routes
-	http.HandleFunc("/admin/login", corsMiddleware(methodMiddleware("POST", handlers.AdminLogin)))
-	http.HandleFunc("/admin/register", corsMiddleware(methodMiddleware("POST", handlers.AdminRegister)))
+	http.HandleFunc("/admin/login", corsMiddleware(rateLimitMiddleware(config.RateLimitAuth, methodMiddleware("POST", handlers.AdminLogin))))
+	http.HandleFunc("/admin/login/2fa", corsMiddleware(rateLimitMiddleware(config.RateLimitAuth, methodMiddleware("POST", handlers.AdminLoginTOTP))))
+	http.HandleFunc("/admin/register", corsMiddleware(rateLimitMiddleware(config.RateLimitAuth, methodMiddleware("POST", handlers.AdminRegister))))
+	http.HandleFunc("/admin/token/refresh", corsMiddleware(rateLimitMiddleware(config.RateLimitAuth, methodMiddleware("POST", handlers.RefreshAccessToken))))
+	http.HandleFunc("/admin/logout", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(rateLimitByMethod, methodMiddleware("POST", handlers.Logout))))))
+
+	// Session routes
+	http.HandleFunc("/admin/sessions", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(rateLimitByMethod, handleSessionRoutes)))))
+	http.HandleFunc("/admin/sessions/", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(rateLimitByMethod, methodMiddleware("DELETE", handlers.RevokeSession))))))
+
+	// OpenID Connect single sign-on (browser redirects)
+	http.HandleFunc("/admin/oidc/login", rateLimitMiddleware(config.RateLimitAuth, methodMiddleware("GET", handlers.OIDCLogin)))
+	http.HandleFunc("/admin/oidc/callback", rateLimitMiddleware(config.RateLimitAuth, methodMiddleware("GET", handlers.OIDCCallback)))
+
+	// Two-factor authentication routes
+	http.HandleFunc("/admin/2fa/setup", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(rateLimitByMethod, methodMiddleware("POST", handlers.SetupTOTP))))))
+	http.HandleFunc("/admin/2fa/enable", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(rateLimitByMethod, methodMiddleware("POST", handlers.EnableTOTP))))))
+	http.HandleFunc("/admin/2fa/disable", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(rateLimitByMethod, methodMiddleware("POST", handlers.DisableTOTP))))))
+	http.HandleFunc("/admin/2fa/recovery-codes", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(rateLimitByMethod, methodMiddleware("POST", handlers.RegenerateRecoveryCodes))))))
+
+	// Admin account routes
+	http.HandleFunc("/admin/admins", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(rateLimitByMethod, methodMiddleware("GET", handlers.GetAdmins))))))
+	http.HandleFunc("/admin/admins/", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(rateLimitByMethod, handleAdminAccountRoutes)))))
+
+	// Registration invite routes
+	http.HandleFunc("/admin/invites", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(rateLimitByMethod, handleRegistrationInviteRoutes)))))
+	http.HandleFunc("/admin/invites/", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(rateLimitByMethod, methodMiddleware("DELETE", handlers.RevokeRegistrationInvite))))))
+
+	// Password routes
+	http.HandleFunc("/admin/password", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(rateLimitByMethod, methodMiddleware("POST", handlers.ChangePassword))))))
+	http.HandleFunc("/admin/password/forgot", corsMiddleware(rateLimitMiddleware(config.RateLimitAuth, methodMiddleware("POST", handlers.ForgotPassword))))
+	http.HandleFunc("/admin/password/reset", corsMiddleware(rateLimitMiddleware(config.RateLimitAuth, methodMiddleware("POST", handlers.ResetPassword))))
+
+	// API token routes
+	http.HandleFunc("/admin/tokens", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(rateLimitByMethod, handleAPITokenRoutes)))))
+	http.HandleFunc("/admin/tokens/", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(rateLimitByMethod, methodMiddleware("DELETE", handlers.RevokeAPIToken))))))
 
 	// House routes
-	http.HandleFunc("/houses", corsMiddleware(handleHouseRoutes))
-	http.HandleFunc("/houses/", corsMiddleware(handleHouseRoutes))
+	http.HandleFunc("/houses", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(rateLimitByMethod, handleHouseRoutes)))))
+	http.HandleFunc("/houses/", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(rateLimitByMethod, handleHouseRoutes)))))
+
+	// Public read-only share links (no login, optional X-Share-Password)
+	http.HandleFunc("/shared/", corsMiddleware(methodMiddleware("GET", handleSharedRoutes)))
+
+	// House invitation routes
+	http.HandleFunc("/invitations", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(rateLimitByMethod, methodMiddleware("GET", handlers.GetInvitations))))))
+	http.HandleFunc("/invitations/", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(rateLimitByMethod, handleInvitationRoutes)))))
 
 	// Person routes
-	http.HandleFunc("/persons", corsMiddleware(handlePersonRoutes))
-	http.HandleFunc("/persons/", corsMiddleware(handlePersonRoutes))
+	http.HandleFunc("/persons", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(rateLimitByMethod, handlePersonRoutes)))))
+	http.HandleFunc("/persons/", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(rateLimitByMethod, handlePersonRoutes)))))
 
 	// Relation routes
-	http.HandleFunc("/relations", corsMiddleware(handleRelationRoutes))
-	http.HandleFunc("/relations/", corsMiddleware(handleRelationRoutes))
+	http.HandleFunc("/relations", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(rateLimitByMethod, handleRelationRoutes)))))
+	http.HandleFunc("/relations/", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(rateLimitByMethod, handleRelationRoutes)))))
+
+	// Event routes
+	http.HandleFunc("/events", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(rateLimitByMethod, handleEventRoutes)))))
+	http.HandleFunc("/events/", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(rateLimitByMethod, handleEventRoutes)))))
+
+	// Place routes
+	http.HandleFunc("/places", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(rateLimitByMethod, handlePlaceRoutes)))))
+	http.HandleFunc("/places/", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(rateLimitByMethod, handlePlaceRoutes)))))
 
 	// Family tree route
-	http.HandleFunc("/family-tree/", corsMiddleware(methodMiddleware("GET", handlers.GetFamilyTree)))
+	http.HandleFunc("/family-tree/", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(config.RateLimitExport, methodMiddleware("GET", handlers.GetFamilyTree))))))
+
+	// Audit log
+	http.HandleFunc("/audit-log", corsMiddleware(ipRateLimitMiddleware(authMiddleware(rateLimitMiddleware(rateLimitByMethod, methodMiddleware("GET", handlers.GetAuditLog))))))
+
+	// Anything else is answered with a JSON 404
+	http.HandleFunc("/", corsMiddleware(rateLimitMiddleware(rateLimitByMethod, handleNotFound)))
 
 	log.Println("Routes registered successfully")
 }
 
-// CORS middleware
+// CORS middleware - applies the origin policy from config.InitCORS
 func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		// The response depends on the Origin header, so caches must key on it
+		w.Header().Add("Vary", "Origin")
+
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != ""
+
+		if origin != "" {
+			if !config.CORSOriginAllowed(origin) {
+				handlers.WriteError(w, r, http.StatusForbidden, handlers.ErrCodeOriginNotAllowed, "Origin not allowed")
+				return
+			}
+
+			if config.CORSAllowsAnyOrigin() && !config.CORSAllowCredentials {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			if config.CORSAllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+			// Let browser clients read the rate limit state and request ID
+			w.Header().Set("Access-Control-Expose-Headers", rateLimitHeaders+", "+requestIDHeader)
+
+			if preflight {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+				w.Header().Set("Access-Control-Allow-Methods", strings.Join(config.CORSAllowedMethods, ", "))
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join(config.CORSAllowedHeaders, ", "))
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(config.CORSMaxAge.Seconds())))
+			}
+		}
 
 		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
+			w.WriteHeader(http.StatusNoContent)
 			return
 		}
 
@@ -46,19 +134,206 @@ func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// Auth middleware - requires a bearer token, either issued by AdminLogin or a personal API token
+func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		token := strings.TrimPrefix(header, "Bearer ")
+		if header == "" || token == header {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			handlers.WriteError(w, r, http.StatusUnauthorized, handlers.ErrCodeTokenMissing, "Missing bearer token")
+			return
+		}
+
+		// Personal API tokens used by scripts and integrations
+		if strings.HasPrefix(token, utils.APITokenPrefix) {
+			apiToken, ok := authenticateAPIToken(token)
+			if !ok {
+				rejectToken(w, r)
+				return
+			}
+
+			// Read-only tokens may only read
+			if apiToken.Scope == models.TokenScopeRead && r.Method != "GET" {
+				handlers.WriteError(w, r, http.StatusForbidden, handlers.ErrCodeTokenReadOnly, "Token scope is read-only")
+				return
+			}
+
+			if !adminEnabled(apiToken.AdminID) {
+				rejectToken(w, r)
+				return
+			}
+
+			ctx := utils.WithAdminID(r.Context(), apiToken.AdminID)
+			next(w, r.WithContext(utils.WithAPIToken(ctx, apiToken.ID, apiToken.Scope)))
+			return
+		}
+
+		claims, err := utils.ParseToken(token)
+		if err != nil {
+			rejectToken(w, r)
+			return
+		}
+
+		// Make sure the admin behind the token still exists and is not disabled,
+		// and that the session it belongs to has not been revoked
+		if !adminEnabled(claims.Subject) || !sessionActive(claims.Session, claims.Subject) {
+			rejectToken(w, r)
+			return
+		}
+
+		ctx := utils.WithAdminID(r.Context(), claims.Subject)
+		next(w, r.WithContext(utils.WithSessionID(ctx, claims.Session)))
+	}
+}
+
+// authenticateAPIToken looks up a personal API token and checks its secret against the stored hash
+func authenticateAPIToken(token string) (*models.APIToken, bool) {
+	id, secret, err := utils.ParseAPIToken(token)
+	if err != nil {
+		return nil, false
+	}
+
+	var apiToken models.APIToken
+	if err := config.DB.First(&apiToken, id).Error; err != nil {
+		return nil, false
+	}
+
+	now := time.Now()
+	if !apiToken.Active(now) {
+		return nil, false
+	}
+	if strings.HasPrefix(apiToken.TokenHash, "$") {
+		// Tokens created before secrets were stored as SHA-256 digests carry a
+		// password hash; the first successful use replaces it
+		if !utils.CheckPasswordHash(secret, apiToken.TokenHash) {
+			return nil, false
+		}
+		apiToken.TokenHash = utils.HashToken(secret)
+		config.DB.Model(&apiToken).UpdateColumn("token_hash", apiToken.TokenHash)
+	} else if !utils.CheckTokenHash(secret, apiToken.TokenHash) {
+		return nil, false
+	}
+
+	config.DB.Model(&apiToken).UpdateColumn("last_used_at", now)
+	return &apiToken, true
+}
+
+// adminEnabled reports whether an admin exists and has not been disabled
+func adminEnabled(id uint) bool {
+	var admin models.Admin
+	if err := config.DB.Select("id", "disabled_at").First(&admin, id).Error; err != nil {
+		return false
+	}
+	return admin.DisabledAt == nil
+}
+
+// sessionActive reports whether a login session belongs to the admin and is still
+// valid, and notes when it was last used
+func sessionActive(id, adminID uint) bool {
+	var session models.Session
+	if err := config.DB.Select("id", "admin_id", "expires_at", "last_used_at", "revoked_at").First(&session, id).Error; err != nil {
+		return false
+	}
+
+	now := time.Now()
+	if session.AdminID != adminID || !session.Active(now) {
+		return false
+	}
+
+	// Once a minute is precise enough for the session list
+	if session.LastUsedAt == nil || now.Sub(*session.LastUsedAt) > time.Minute {
+		config.DB.Model(&session).UpdateColumn("last_used_at", now)
+	}
+	return true
+}
+
+func rejectToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	handlers.WriteError(w, r, http.StatusUnauthorized, handlers.ErrCodeTokenInvalid, "Invalid or expired token")
+}
+
 // Method middleware
 func methodMiddleware(allowedMethod string, handler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != allowedMethod {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			handlers.WriteError(w, r, http.StatusMethodNotAllowed, handlers.ErrCodeMethodNotAllowed, "Method not allowed")
 			return
 		}
 		handler(w, r)
 	}
 }
 
+// Fallback for paths no route matches
+func handleNotFound(w http.ResponseWriter, r *http.Request) {
+	handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
+}
+
+// Admin account route handler - /admin/admins/{id}/...
+func handleAdminAccountRoutes(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/unlock"):
+		handlers.UnlockAdmin(w, r)
+	case r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/disable"):
+		handlers.DisableAdmin(w, r)
+	case r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/enable"):
+		handlers.EnableAdmin(w, r)
+	case r.Method == "PUT" && !strings.Contains(strings.TrimPrefix(r.URL.Path, "/admin/admins/"), "/"):
+		handlers.UpdateAdmin(w, r)
+	case r.Method == "DELETE" && !strings.Contains(strings.TrimPrefix(r.URL.Path, "/admin/admins/"), "/"):
+		handlers.DeleteAdmin(w, r)
+	default:
+		handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
+	}
+}
+
+// Registration invite route handler
+func handleRegistrationInviteRoutes(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		handlers.GetRegistrationInvites(w, r)
+	case "POST":
+		handlers.CreateRegistrationInvite(w, r)
+	default:
+		handlers.WriteError(w, r, http.StatusMethodNotAllowed, handlers.ErrCodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// Session route handler
+func handleSessionRoutes(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		handlers.GetSessions(w, r)
+	case "DELETE":
+		handlers.RevokeOtherSessions(w, r)
+	default:
+		handlers.WriteError(w, r, http.StatusMethodNotAllowed, handlers.ErrCodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// API token route handler
+func handleAPITokenRoutes(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		handlers.GetAPITokens(w, r)
+	case "POST":
+		handlers.CreateAPIToken(w, r)
+	default:
+		handlers.WriteError(w, r, http.StatusMethodNotAllowed, handlers.ErrCodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
 // House route handler
 func handleHouseRoutes(w http.ResponseWriter, r *http.Request) {
+	if strings.Contains(strings.TrimPrefix(r.URL.Path, "/houses/"), "/members") {
+		handleHouseMemberRoutes(w, r)
+		return
+	}
+	if strings.Contains(strings.TrimPrefix(r.URL.Path, "/houses/"), "/share-links") {
+		handleShareLinkRoutes(w, r)
+		return
+	}
+
 	switch r.Method {
 	case "GET":
 		if strings.HasPrefix(r.URL.Path, "/houses/") && len(strings.TrimPrefix(r.URL.Path, "/houses/")) > 0 {
@@ -70,27 +345,121 @@ func handleHouseRoutes(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/houses" {
 			handlers.CreateHouse(w, r)
 		} else {
-			http.Error(w, "Not found", http.StatusNotFound)
+			handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
 		}
 	case "PUT":
 		if strings.HasPrefix(r.URL.Path, "/houses/") {
 			handlers.UpdateHouse(w, r)
 		} else {
-			http.Error(w, "Not found", http.StatusNotFound)
+			handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
 		}
 	case "DELETE":
 		if strings.HasPrefix(r.URL.Path, "/houses/") {
 			handlers.DeleteHouse(w, r)
 		} else {
-			http.Error(w, "Not found", http.StatusNotFound)
+			handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
+		}
+	default:
+		handlers.WriteError(w, r, http.StatusMethodNotAllowed, handlers.ErrCodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// House member route handler - /houses/{id}/members[/{admin_id}]
+func handleHouseMemberRoutes(w http.ResponseWriter, r *http.Request) {
+	hasMember := !strings.HasSuffix(strings.TrimSuffix(r.URL.Path, "/"), "/members")
+
+	switch r.Method {
+	case "GET":
+		if !hasMember {
+			handlers.GetHouseMembers(w, r)
+		} else {
+			handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
+		}
+	case "POST":
+		if !hasMember {
+			handlers.InviteHouseMember(w, r)
+		} else {
+			handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
+		}
+	case "PUT":
+		if hasMember {
+			handlers.UpdateHouseMember(w, r)
+		} else {
+			handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
+		}
+	case "DELETE":
+		if hasMember {
+			handlers.RemoveHouseMember(w, r)
+		} else {
+			handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
+		}
+	default:
+		handlers.WriteError(w, r, http.StatusMethodNotAllowed, handlers.ErrCodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// Share link route handler - /houses/{id}/share-links[/{link_id}]
+func handleShareLinkRoutes(w http.ResponseWriter, r *http.Request) {
+	hasLink := !strings.HasSuffix(strings.TrimSuffix(r.URL.Path, "/"), "/share-links")
+
+	switch r.Method {
+	case "GET":
+		if !hasLink {
+			handlers.GetShareLinks(w, r)
+		} else {
+			handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
+		}
+	case "POST":
+		if !hasLink {
+			handlers.CreateShareLink(w, r)
+		} else {
+			handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
 		}
+	case "DELETE":
+		if hasLink {
+			handlers.RevokeShareLink(w, r)
+		} else {
+			handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
+		}
+	default:
+		handlers.WriteError(w, r, http.StatusMethodNotAllowed, handlers.ErrCodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// Shared route handler - /shared/{token}/family-tree|persons
+func handleSharedRoutes(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/family-tree"):
+		rateLimitMiddleware(config.RateLimitExport, handlers.GetSharedFamilyTree)(w, r)
+	case strings.HasSuffix(r.URL.Path, "/persons"):
+		rateLimitMiddleware(config.RateLimitRead, handlers.GetSharedPersons)(w, r)
+	default:
+		handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
+	}
+}
+
+// Invitation route handler - /invitations/{id}[/accept]
+func handleInvitationRoutes(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		if strings.HasSuffix(r.URL.Path, "/accept") {
+			handlers.AcceptInvitation(w, r)
+		} else {
+			handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
+		}
+	case "DELETE":
+		handlers.DeclineInvitation(w, r)
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		handlers.WriteError(w, r, http.StatusMethodNotAllowed, handlers.ErrCodeMethodNotAllowed, "Method not allowed")
 	}
 }
 
 // Person route handler
 func handlePersonRoutes(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/names") || strings.Contains(r.URL.Path, "/names/") {
+		handlePersonNameRoutes(w, r)
+		return
+	}
 	
 	switch r.Method {
 	case "GET":
@@ -103,22 +472,22 @@ func handlePersonRoutes(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/persons" {
 			handlers.CreatePerson(w, r)
 		} else {
-			http.Error(w, "Not found", http.StatusNotFound)
+			handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
 		}
 	case "PUT":
 		if strings.HasPrefix(r.URL.Path, "/persons/") {
 			handlers.UpdatePerson(w, r)
 		} else {
-			http.Error(w, "Not found", http.StatusNotFound)
+			handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
 		}
 	case "DELETE":
 		if strings.HasPrefix(r.URL.Path, "/persons/") {
 			handlers.DeletePerson(w, r)
 		} else {
-			http.Error(w, "Not found", http.StatusNotFound)
+			handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
 		}
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		handlers.WriteError(w, r, http.StatusMethodNotAllowed, handlers.ErrCodeMethodNotAllowed, "Method not allowed")
 	}
 }
 
@@ -135,21 +504,124 @@ func handleRelationRoutes(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/relations" {
 			handlers.CreateRelation(w, r)
 		} else {
-			http.Error(w, "Not found", http.StatusNotFound)
+			handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
 		}
 	case "PUT":
 		if strings.HasPrefix(r.URL.Path, "/relations/") {
 			handlers.UpdateRelation(w, r)
 		} else {
-			http.Error(w, "Not found", http.StatusNotFound)
+			handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
 		}
 	case "DELETE":
 		if strings.HasPrefix(r.URL.Path, "/relations/") {
 			handlers.DeleteRelation(w, r)
 		} else {
-			http.Error(w, "Not found", http.StatusNotFound)
+			handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
+		}
+	default:
+		handlers.WriteError(w, r, http.StatusMethodNotAllowed, handlers.ErrCodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// Event route handler
+func handleEventRoutes(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		if strings.HasPrefix(r.URL.Path, "/events/") && len(strings.TrimPrefix(r.URL.Path, "/events/")) > 0 {
+			handlers.GetEvent(w, r)
+		} else {
+			handlers.GetEvents(w, r)
+		}
+	case "POST":
+		if r.URL.Path == "/events" {
+			handlers.CreateEvent(w, r)
+		} else {
+			handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
+		}
+	case "PUT":
+		if strings.HasPrefix(r.URL.Path, "/events/") {
+			handlers.UpdateEvent(w, r)
+		} else {
+			handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
+		}
+	case "DELETE":
+		if strings.HasPrefix(r.URL.Path, "/events/") {
+			handlers.DeleteEvent(w, r)
+		} else {
+			handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
+		}
+	default:
+		handlers.WriteError(w, r, http.StatusMethodNotAllowed, handlers.ErrCodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// Place route handler - /places[/{id}[/merge]]
+func handlePlaceRoutes(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/merge") {
+		methodMiddleware("POST", handlers.MergePlace)(w, r)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		if strings.HasPrefix(r.URL.Path, "/places/") && len(strings.TrimPrefix(r.URL.Path, "/places/")) > 0 {
+			handlers.GetPlace(w, r)
+		} else {
+			handlers.GetPlaces(w, r)
+		}
+	case "POST":
+		if r.URL.Path == "/places" {
+			handlers.CreatePlace(w, r)
+		} else {
+			handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
+		}
+	case "PUT":
+		if strings.HasPrefix(r.URL.Path, "/places/") {
+			handlers.UpdatePlace(w, r)
+		} else {
+			handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
+		}
+	case "DELETE":
+		if strings.HasPrefix(r.URL.Path, "/places/") {
+			handlers.DeletePlace(w, r)
+		} else {
+			handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
+		}
+	default:
+		handlers.WriteError(w, r, http.StatusMethodNotAllowed, handlers.ErrCodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// Person name route handler - /persons/{id}/names[/{nameId}]
+func handlePersonNameRoutes(w http.ResponseWriter, r *http.Request) {
+	collection := strings.HasSuffix(r.URL.Path, "/names")
+
+	switch r.Method {
+	case "GET":
+		if collection {
+			handlers.GetPersonNames(w, r)
+		} else {
+			handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
+		}
+	case "POST":
+		if collection {
+			handlers.CreatePersonName(w, r)
+		} else {
+			handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
+		}
+	case "PUT":
+		if !collection {
+			handlers.UpdatePersonName(w, r)
+		} else {
+			handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
+		}
+	case "DELETE":
+		if !collection {
+			handlers.DeletePersonName(w, r)
+		} else {
+			handlers.WriteError(w, r, http.StatusNotFound, handlers.ErrCodeNotFound, "Not found")
 		}
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		handlers.WriteError(w, r, http.StatusMethodNotAllowed, handlers.ErrCodeMethodNotAllowed, "Method not allowed")
 	}
 }
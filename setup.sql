@@ -14,6 +14,92 @@ CREATE TABLE IF NOT EXISTS admins (
     id SERIAL PRIMARY KEY,
     username TEXT UNIQUE NOT NULL,
     password TEXT NOT NULL, -- hashed
+    email TEXT UNIQUE, -- optional, used for password resets
+    locked_until TIMESTAMP, -- set after repeated failed logins
+    is_superadmin BOOLEAN NOT NULL DEFAULT FALSE, -- may manage admins and registration invites
+    disabled_at TIMESTAMP, -- disabled admins cannot log in
+    totp_secret TEXT, -- base32 TOTP secret, set during 2FA enrollment
+    totp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    totp_last_counter BIGINT NOT NULL DEFAULT 0, -- last accepted TOTP time step
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Identities at external OpenID providers linked to admins
+CREATE TABLE IF NOT EXISTS admin_identities (
+    id SERIAL PRIMARY KEY,
+    admin_id INTEGER NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    issuer TEXT NOT NULL,
+    subject TEXT NOT NULL, -- the provider's "sub" claim
+    email TEXT,
+    last_login_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(issuer, subject)
+);
+
+-- Login sessions (one per sign-in, revocable)
+CREATE TABLE IF NOT EXISTS sessions (
+    id SERIAL PRIMARY KEY,
+    admin_id INTEGER NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    device TEXT, -- short description derived from the user agent
+    user_agent TEXT,
+    ip_address TEXT, -- client IP of the latest login or refresh
+    expires_at TIMESTAMP NOT NULL, -- absolute limit, refreshing cannot extend past it
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Refresh tokens (single-use, rotated on every refresh)
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    session_id INTEGER NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    token_hash TEXT UNIQUE NOT NULL, -- SHA-256 of the refresh token
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP, -- set when rotated; presenting it again revokes the session
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Registration invites (single-use, needed once the first admin exists)
+CREATE TABLE IF NOT EXISTS registration_invites (
+    id SERIAL PRIMARY KEY,
+    token_hash TEXT UNIQUE NOT NULL, -- SHA-256 of the invite token
+    email TEXT, -- if set, the new account must use this address
+    created_by INTEGER REFERENCES admins(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    used_by INTEGER REFERENCES admins(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Two-factor recovery codes table (single-use)
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id SERIAL PRIMARY KEY,
+    admin_id INTEGER NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL, -- SHA-256 of the normalized code
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Password reset tokens table (single-use, time-limited)
+CREATE TABLE IF NOT EXISTS password_resets (
+    id SERIAL PRIMARY KEY,
+    admin_id INTEGER NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    token_hash TEXT UNIQUE NOT NULL, -- SHA-256 of the emailed token
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- API tokens table (personal tokens for scripts and integrations)
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id SERIAL PRIMARY KEY,
+    admin_id INTEGER NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL, -- hashed
+    scope TEXT NOT NULL DEFAULT 'read' CHECK (scope IN ('read', 'read-write')),
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
     created_at TIMESTAMP DEFAULT NOW()
 );
 
@@ -22,9 +108,62 @@ CREATE TABLE IF NOT EXISTS houses (
     id SERIAL PRIMARY KEY,
     name TEXT NOT NULL,
     created_by INTEGER NOT NULL REFERENCES admins(id),
+    created_at TIMESTAMP DEFAULT NOW(),
+    privacy_min_role TEXT NOT NULL DEFAULT 'editor' CHECK (privacy_min_role IN ('owner', 'editor', 'viewer')), -- lowest role that sees living persons in full
+    privacy_living_years INTEGER NOT NULL DEFAULT 100 CHECK (privacy_living_years > 0) -- persons born within this many years are presumed living
+);
+
+-- House members table (admins sharing a house, with their role)
+CREATE TABLE IF NOT EXISTS house_members (
+    id SERIAL PRIMARY KEY,
+    house_id INTEGER NOT NULL REFERENCES houses(id) ON DELETE CASCADE,
+    admin_id INTEGER NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'active')),
+    invited_by INTEGER REFERENCES admins(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    accepted_at TIMESTAMP,
+    UNIQUE(house_id, admin_id)
+);
+
+-- Share links (read-only public access to a house)
+CREATE TABLE IF NOT EXISTS share_links (
+    id SERIAL PRIMARY KEY,
+    house_id INTEGER NOT NULL REFERENCES houses(id) ON DELETE CASCADE,
+    name TEXT, -- label shown to owners
+    token_hash TEXT UNIQUE NOT NULL, -- SHA-256 of the link token
+    password_hash TEXT, -- optional password, hashed like admin passwords
+    expires_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    use_count INTEGER NOT NULL DEFAULT 0,
+    last_used_at TIMESTAMP,
+    created_by INTEGER REFERENCES admins(id) ON DELETE SET NULL,
     created_at TIMESTAMP DEFAULT NOW()
 );
 
+-- Places table (village -> district -> province -> country)
+CREATE TABLE IF NOT EXISTS places (
+    id SERIAL PRIMARY KEY,
+    house_id INTEGER NOT NULL REFERENCES houses(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    place_type TEXT NOT NULL CHECK (place_type IN ('village', 'district', 'province', 'country')),
+    parent_id INTEGER REFERENCES places(id) ON DELETE SET NULL, -- enclosing place
+    latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90),
+    longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180),
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Alternate and historical names of places, e.g. Batavia for Jakarta
+CREATE TABLE IF NOT EXISTS place_names (
+    id SERIAL PRIMARY KEY,
+    place_id INTEGER NOT NULL REFERENCES places(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('alternate', 'historical')),
+    language TEXT,
+    from_date TEXT, -- genealogical dates as entered
+    to_date TEXT
+);
+
 -- Persons table
 CREATE TABLE IF NOT EXISTS persons (
     id SERIAL PRIMARY KEY,
@@ -32,8 +171,29 @@ CREATE TABLE IF NOT EXISTS persons (
     name TEXT NOT NULL,
     contact TEXT,
     description TEXT,
-    gender TEXT CHECK (gender IN ('male', 'female')),
-    dob DATE,
+    gender TEXT NOT NULL DEFAULT 'unknown' CHECK (gender ~ '^[a-z][a-z0-9_-]*$'), -- one of GENDERS
+    dob TEXT, -- as entered, e.g. '1925-03-15', 'March 1925' or 'about 1890'
+    dob_sort DATE, -- first day dob can stand for, for ordering
+    dod TEXT, -- date of death, same formats as dob
+    dod_sort DATE,
+    birth_place_id INTEGER REFERENCES places(id) ON DELETE SET NULL,
+    death_place_id INTEGER REFERENCES places(id) ON DELETE SET NULL,
+    is_living BOOLEAN, -- NULL means decide from dates
+    cause_of_death TEXT,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Person names table (birth, married, nickname, alias)
+CREATE TABLE IF NOT EXISTS person_names (
+    id SERIAL PRIMARY KEY,
+    person_id INTEGER NOT NULL REFERENCES persons(id) ON DELETE CASCADE,
+    name_type TEXT NOT NULL CHECK (name_type IN ('birth', 'married', 'nickname', 'alias')),
+    prefix TEXT,
+    given_name TEXT,
+    surname TEXT,
+    suffix TEXT,
+    language TEXT, -- language or script, e.g. 'zh-Hant'
+    preferred BOOLEAN NOT NULL DEFAULT FALSE,
     created_at TIMESTAMP DEFAULT NOW()
 );
 
@@ -48,12 +208,73 @@ CREATE TABLE IF NOT EXISTS relations (
     UNIQUE(person_id, related_to_id, relation_type)
 );
 
+-- Events table (birth, baptism, marriage, ...)
+CREATE TABLE IF NOT EXISTS events (
+    id SERIAL PRIMARY KEY,
+    house_id INTEGER NOT NULL REFERENCES houses(id) ON DELETE CASCADE,
+    event_type TEXT NOT NULL CHECK (event_type IN ('birth', 'baptism', 'marriage', 'divorce', 'migration', 'residence', 'occupation', 'death', 'burial')),
+    date TEXT, -- genealogical date as entered, e.g. 'about 1890'
+    date_sort DATE, -- first day date can stand for, for ordering
+    place TEXT, -- as written in the source
+    place_id INTEGER REFERENCES places(id) ON DELETE SET NULL,
+    description TEXT,
+    relation_id INTEGER REFERENCES relations(id) ON DELETE SET NULL, -- spouse relation of a marriage or divorce
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Persons taking part in an event
+CREATE TABLE IF NOT EXISTS event_participants (
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    person_id INTEGER NOT NULL REFERENCES persons(id) ON DELETE CASCADE,
+    role TEXT, -- e.g. principal, spouse, witness, godparent
+    PRIMARY KEY (event_id, person_id)
+);
+
+-- Audit log (one row per create, update or delete; kept after the entity is gone)
+CREATE TABLE IF NOT EXISTS audit_log (
+    id SERIAL PRIMARY KEY,
+    house_id INTEGER,
+    actor_id INTEGER NOT NULL,
+    actor_username TEXT,
+    api_token_id INTEGER,
+    action TEXT NOT NULL CHECK (action IN ('create', 'update', 'delete')),
+    entity_type TEXT NOT NULL,
+    entity_id INTEGER NOT NULL,
+    before JSONB,
+    after JSONB,
+    client_ip TEXT,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
 -- Indexes for better performance
+CREATE INDEX idx_admin_identities_admin_id ON admin_identities(admin_id);
+CREATE INDEX idx_sessions_admin_id ON sessions(admin_id);
+CREATE INDEX idx_refresh_tokens_session_id ON refresh_tokens(session_id);
+CREATE INDEX idx_recovery_codes_admin_id ON recovery_codes(admin_id);
+CREATE INDEX idx_password_resets_admin_id ON password_resets(admin_id);
+CREATE INDEX idx_api_tokens_admin_id ON api_tokens(admin_id);
 CREATE INDEX idx_houses_created_by ON houses(created_by);
+CREATE INDEX idx_house_members_admin_id ON house_members(admin_id);
+CREATE INDEX idx_share_links_house_id ON share_links(house_id);
 CREATE INDEX idx_persons_house_id ON persons(house_id);
+CREATE INDEX idx_places_house_id ON places(house_id);
+CREATE INDEX idx_places_parent_id ON places(parent_id);
+CREATE INDEX idx_place_names_place_id ON place_names(place_id);
+CREATE INDEX idx_persons_birth_place_id ON persons(birth_place_id);
+CREATE INDEX idx_persons_death_place_id ON persons(death_place_id);
+CREATE INDEX idx_person_names_person_id ON person_names(person_id);
+CREATE UNIQUE INDEX idx_person_names_preferred ON person_names(person_id) WHERE preferred;
 CREATE INDEX idx_relations_house_id ON relations(house_id);
 CREATE INDEX idx_relations_person_id ON relations(person_id);
 CREATE INDEX idx_relations_related_to_id ON relations(related_to_id);
+CREATE INDEX idx_events_house_id ON events(house_id);
+CREATE INDEX idx_events_relation_id ON events(relation_id);
+CREATE INDEX idx_events_place_id ON events(place_id);
+CREATE INDEX idx_event_participants_person_id ON event_participants(person_id);
+CREATE INDEX idx_audit_log_house_id ON audit_log(house_id);
+CREATE INDEX idx_audit_log_entity ON audit_log(entity_type, entity_id);
+CREATE INDEX idx_audit_log_actor_id ON audit_log(actor_id);
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at);
 
 -- Sample data (optional)
 -- INSERT INTO admins (username, password) VALUES ('admin', '$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi'); -- password: 'password'
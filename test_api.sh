@@ -9,45 +9,56 @@ echo "🌳 GoFamTree API Testing Script"
 echo "================================"
 
 # Test 1: Register Admin
+# Only the first admin can register freely; set INVITE_TOKEN to register on an existing installation
 echo "📝 1. Registering admin..."
 curl -X POST "$BASE_URL/admin/register" \
   -H "Content-Type: application/json" \
   -d '{
     "username": "testadmin",
-    "password": "password123"
+    "password": "password123",
+    "invite_token": "'"$INVITE_TOKEN"'"
   }' | jq .
 echo ""
 
 # Test 2: Login Admin
-echo "🚪 2. Admin login..."
-curl -X POST "$BASE_URL/admin/login" \
-  -H "Content-Type: application/json" \
-  -d '{
-    "username": "testadmin",
-    "password": "password123"
-  }' | jq .
+# Set API_TOKEN to a personal API token to skip the interactive login
+if [ -n "$API_TOKEN" ]; then
+  echo "🚪 2. Using API token from \$API_TOKEN..."
+  TOKEN="$API_TOKEN"
+else
+  echo "🚪 2. Admin login..."
+  LOGIN_RESPONSE=$(curl -s -X POST "$BASE_URL/admin/login" \
+    -H "Content-Type: application/json" \
+    -d '{
+      "username": "testadmin",
+      "password": "password123"
+    }')
+  echo "$LOGIN_RESPONSE" | jq .
+  TOKEN=$(echo "$LOGIN_RESPONSE" | jq -r .token)
+  REFRESH_TOKEN=$(echo "$LOGIN_RESPONSE" | jq -r .refresh_token)
+fi
+AUTH_HEADER="Authorization: Bearer $TOKEN"
 echo ""
 
 # Test 3: Create House
 echo "🏠 3. Creating house..."
-curl -X POST "$BASE_URL/houses" \
+curl -X POST "$BASE_URL/houses" -H "$AUTH_HEADER" \
   -H "Content-Type: application/json" \
   -d '{
-    "name": "Smith Family",
-    "created_by": 1
+    "name": "Smith Family"
   }' | jq .
 echo ""
 
 # Test 4: Get All Houses
 echo "📋 4. Getting all houses..."
-curl -X GET "$BASE_URL/houses" | jq .
+curl -X GET "$BASE_URL/houses" -H "$AUTH_HEADER" | jq .
 echo ""
 
 # Test 5: Create Persons
 echo "👥 5. Creating persons..."
 
 # Father
-curl -X POST "$BASE_URL/persons" \
+curl -X POST "$BASE_URL/persons" -H "$AUTH_HEADER" \
   -H "Content-Type: application/json" \
   -d '{
     "house_id": 1,
@@ -60,7 +71,7 @@ curl -X POST "$BASE_URL/persons" \
 echo ""
 
 # Mother
-curl -X POST "$BASE_URL/persons" \
+curl -X POST "$BASE_URL/persons" -H "$AUTH_HEADER" \
   -H "Content-Type: application/json" \
   -d '{
     "house_id": 1,
@@ -73,7 +84,7 @@ curl -X POST "$BASE_URL/persons" \
 echo ""
 
 # Child
-curl -X POST "$BASE_URL/persons" \
+curl -X POST "$BASE_URL/persons" -H "$AUTH_HEADER" \
   -H "Content-Type: application/json" \
   -d '{
     "house_id": 1,
@@ -87,14 +98,14 @@ echo ""
 
 # Test 6: Get All Persons
 echo "📋 6. Getting all persons..."
-curl -X GET "$BASE_URL/persons" | jq .
+curl -X GET "$BASE_URL/persons" -H "$AUTH_HEADER" | jq .
 echo ""
 
 # Test 7: Create Relations
 echo "🔗 7. Creating relations..."
 
 # Spouse relation
-curl -X POST "$BASE_URL/relations" \
+curl -X POST "$BASE_URL/relations" -H "$AUTH_HEADER" \
   -H "Content-Type: application/json" \
   -d '{
     "house_id": 1,
@@ -105,7 +116,7 @@ curl -X POST "$BASE_URL/relations" \
 echo ""
 
 # Parent-child relation (John -> Alice)
-curl -X POST "$BASE_URL/relations" \
+curl -X POST "$BASE_URL/relations" -H "$AUTH_HEADER" \
   -H "Content-Type: application/json" \
   -d '{
     "house_id": 1,
@@ -116,7 +127,7 @@ curl -X POST "$BASE_URL/relations" \
 echo ""
 
 # Parent-child relation (Jane -> Alice)
-curl -X POST "$BASE_URL/relations" \
+curl -X POST "$BASE_URL/relations" -H "$AUTH_HEADER" \
   -H "Content-Type: application/json" \
   -d '{
     "house_id": 1,
@@ -128,17 +139,17 @@ echo ""
 
 # Test 8: Get All Relations
 echo "📋 8. Getting all relations..."
-curl -X GET "$BASE_URL/relations" | jq .
+curl -X GET "$BASE_URL/relations" -H "$AUTH_HEADER" | jq .
 echo ""
 
 # Test 9: Get Family Tree
 echo "🌳 9. Getting family tree for house 1..."
-curl -X GET "$BASE_URL/family-tree/1" | jq .
+curl -X GET "$BASE_URL/family-tree/1" -H "$AUTH_HEADER" | jq .
 echo ""
 
 # Test 10: Update Person
 echo "✏️ 10. Updating person..."
-curl -X PUT "$BASE_URL/persons/1" \
+curl -X PUT "$BASE_URL/persons/1" -H "$AUTH_HEADER" \
   -H "Content-Type: application/json" \
   -d '{
     "name": "John Smith Sr.",
@@ -151,7 +162,92 @@ echo ""
 
 # Test 11: Get Updated Person
 echo "👁️ 11. Getting updated person..."
-curl -X GET "$BASE_URL/persons/1" | jq .
+curl -X GET "$BASE_URL/persons/1" -H "$AUTH_HEADER" | jq .
+echo ""
+
+# Test 12: Refresh Token and Sessions (login tokens only)
+if [ -n "$REFRESH_TOKEN" ]; then
+  echo "🔄 12. Refreshing the bearer token and listing sessions..."
+  REFRESH_RESPONSE=$(curl -s -X POST "$BASE_URL/admin/token/refresh" \
+    -H "Content-Type: application/json" \
+    -d '{"refresh_token": "'"$REFRESH_TOKEN"'"}')
+  echo "$REFRESH_RESPONSE" | jq .
+  AUTH_HEADER="Authorization: Bearer $(echo "$REFRESH_RESPONSE" | jq -r .token)"
+  curl -X GET "$BASE_URL/admin/sessions" -H "$AUTH_HEADER" | jq .
+  echo ""
+fi
+
+# Test 13: Validation Errors
+echo "🚫 13. Creating an invalid person (expect 422)..."
+curl -X POST "$BASE_URL/persons" -H "$AUTH_HEADER" \
+  -H "Content-Type: application/json" \
+  -d '{
+    "house_id": 1,
+    "name": "",
+    "gender": "robot",
+    "dob": "15/01/1980",
+    "nickname": "Johnny"
+  }' | jq .
+echo ""
+
+# Test 14: Life Events
+echo "📅 14. Recording a marriage and a birth..."
+curl -X POST "$BASE_URL/events" -H "$AUTH_HEADER" \
+  -H "Content-Type: application/json" \
+  -d '{
+    "house_id": 1,
+    "event_type": "marriage",
+    "date": "June 2004",
+    "place": "Springfield",
+    "relation_id": 1
+  }' | jq .
+curl -X POST "$BASE_URL/events" -H "$AUTH_HEADER" \
+  -H "Content-Type: application/json" \
+  -d '{
+    "house_id": 1,
+    "event_type": "birth",
+    "date": "about 2010",
+    "participants": [{"person_id": 3, "role": "principal"}]
+  }' | jq .
+curl -X GET "$BASE_URL/events?person_id=1" -H "$AUTH_HEADER" | jq .
+echo ""
+
+# Test 15: Places
+echo "🗺️ 15. Creating places and searching by historical name..."
+DISTRICT_RESPONSE=$(curl -s -X POST "$BASE_URL/places" -H "$AUTH_HEADER" \
+  -H "Content-Type: application/json" \
+  -d '{
+    "house_id": 1,
+    "name": "Morris County",
+    "place_type": "district"
+  }')
+echo "$DISTRICT_RESPONSE" | jq .
+DISTRICT_ID=$(echo "$DISTRICT_RESPONSE" | jq -r .id)
+VILLAGE_RESPONSE=$(curl -s -X POST "$BASE_URL/places" -H "$AUTH_HEADER" \
+  -H "Content-Type: application/json" \
+  -d '{
+    "house_id": 1,
+    "name": "Chatham",
+    "place_type": "village",
+    "parent_id": '"$DISTRICT_ID"',
+    "names": [{"name": "Chatham Township", "kind": "historical", "to": "1870"}]
+  }')
+echo "$VILLAGE_RESPONSE" | jq .
+VILLAGE_ID=$(echo "$VILLAGE_RESPONSE" | jq -r .id)
+curl -X GET "$BASE_URL/places?q=township" -H "$AUTH_HEADER" | jq .
+curl -X GET "$BASE_URL/persons?place_id=$VILLAGE_ID" -H "$AUTH_HEADER" | jq .
+echo ""
+
+# Test 16: Person Names
+echo "🪪 16. Adding Jane's maiden name and searching by it..."
+curl -X POST "$BASE_URL/persons/2/names" -H "$AUTH_HEADER" \
+  -H "Content-Type: application/json" \
+  -d '{
+    "name_type": "birth",
+    "given_name": "Jane",
+    "surname": "Carter"
+  }' | jq .
+curl -X GET "$BASE_URL/persons?q=carter" -H "$AUTH_HEADER" | jq .
 echo ""
 
 echo "✅ API Testing completed!"
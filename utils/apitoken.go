@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// APITokenPrefix marks personal API tokens so they can be told apart from login tokens
+const APITokenPrefix = "gft_"
+
+var ErrMalformedAPIToken = errors.New("malformed API token")
+
+// NewAPITokenSecret returns a random secret for a personal API token
+func NewAPITokenSecret() (string, error) {
+	return RandomToken()
+}
+
+// FormatAPIToken builds the token handed to the user: gft_<id>_<secret>
+func FormatAPIToken(id uint, secret string) string {
+	return fmt.Sprintf("%s%d_%s", APITokenPrefix, id, secret)
+}
+
+// ParseAPIToken splits a personal API token into its row ID and secret
+func ParseAPIToken(token string) (uint, string, error) {
+	rest := strings.TrimPrefix(token, APITokenPrefix)
+	if rest == token {
+		return 0, "", ErrMalformedAPIToken
+	}
+
+	idPart, secret, found := strings.Cut(rest, "_")
+	if !found || secret == "" {
+		return 0, "", ErrMalformedAPIToken
+	}
+
+	id, err := strconv.ParseUint(idPart, 10, 32)
+	if err != nil || id == 0 {
+		return 0, "", ErrMalformedAPIToken
+	}
+	return uint(id), secret, nil
+}
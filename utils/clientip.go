@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"gofamtree/config"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ClientIP returns the caller's IP address. Proxy headers are only honoured when
+// TRUST_PROXY_HEADERS is enabled, otherwise any client could spoof them.
+func ClientIP(r *http.Request) string {
+	if config.TrustProxyHeaders {
+		if forwarded := forwardedFor(r, config.TrustedProxyHops); forwarded != "" {
+			return forwarded
+		}
+		if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+			return strings.TrimSpace(realIP)
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// forwardedFor picks the X-Forwarded-For entry added by the outermost of hops
+// trusted proxies. Each proxy appends the address it received the request from,
+// so entries to the left of that one come from the client and prove nothing.
+func forwardedFor(r *http.Request, hops int) string {
+	var entries []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, entry := range strings.Split(header, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	if len(entries) == 0 {
+		return ""
+	}
+	if hops > len(entries) {
+		return entries[0]
+	}
+	return entries[len(entries)-hops]
+}
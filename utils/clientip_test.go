@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"gofamtree/config"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name      string
+		trust     bool
+		hops      int
+		forwarded []string
+		realIP    string
+		want      string
+	}{
+		{name: "proxy headers ignored", trust: false, hops: 1, forwarded: []string{"203.0.113.7"}, want: "192.0.2.1"},
+		{name: "one proxy", trust: true, hops: 1, forwarded: []string{"203.0.113.7"}, want: "203.0.113.7"},
+		{name: "spoofed entry on the left", trust: true, hops: 1, forwarded: []string{"10.0.0.1, 203.0.113.7"}, want: "203.0.113.7"},
+		{name: "two proxies", trust: true, hops: 2, forwarded: []string{"10.0.0.1, 203.0.113.7, 198.51.100.2"}, want: "203.0.113.7"},
+		{name: "repeated headers", trust: true, hops: 1, forwarded: []string{"10.0.0.1", "203.0.113.7"}, want: "203.0.113.7"},
+		{name: "fewer entries than hops", trust: true, hops: 3, forwarded: []string{"203.0.113.7, 198.51.100.2"}, want: "203.0.113.7"},
+		{name: "real IP fallback", trust: true, hops: 1, realIP: " 203.0.113.9 ", want: "203.0.113.9"},
+		{name: "no headers", trust: true, hops: 1, want: "192.0.2.1"},
+	}
+
+	defer func(trust bool, hops int) {
+		config.TrustProxyHeaders, config.TrustedProxyHops = trust, hops
+	}(config.TrustProxyHeaders, config.TrustedProxyHops)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.TrustProxyHeaders, config.TrustedProxyHops = tt.trust, tt.hops
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = "192.0.2.1:51234"
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := ClientIP(r); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package utils
+
+import "context"
+
+type contextKey string
+
+const (
+	adminIDKey    contextKey = "admin_id"
+	apiTokenIDKey contextKey = "api_token_id"
+	tokenScopeKey contextKey = "token_scope"
+	sessionIDKey  contextKey = "session_id"
+	requestIDKey  contextKey = "request_id"
+)
+
+// WithAdminID stores the authenticated admin ID on the request context
+func WithAdminID(ctx context.Context, adminID uint) context.Context {
+	return context.WithValue(ctx, adminIDKey, adminID)
+}
+
+// AdminIDFromContext returns the authenticated admin ID, or 0 if the request is anonymous
+func AdminIDFromContext(ctx context.Context) uint {
+	adminID, _ := ctx.Value(adminIDKey).(uint)
+	return adminID
+}
+
+// WithAPIToken records that the request authenticated with a personal API token
+func WithAPIToken(ctx context.Context, tokenID uint, scope string) context.Context {
+	ctx = context.WithValue(ctx, apiTokenIDKey, tokenID)
+	return context.WithValue(ctx, tokenScopeKey, scope)
+}
+
+// APITokenFromContext returns the personal API token behind the request, or 0 and ""
+// for interactive logins
+func APITokenFromContext(ctx context.Context) (uint, string) {
+	tokenID, _ := ctx.Value(apiTokenIDKey).(uint)
+	scope, _ := ctx.Value(tokenScopeKey).(string)
+	return tokenID, scope
+}
+
+// WithSessionID records the login session behind an access token
+func WithSessionID(ctx context.Context, sessionID uint) context.Context {
+	return context.WithValue(ctx, sessionIDKey, sessionID)
+}
+
+// SessionIDFromContext returns the caller's login session, or 0 for API tokens
+func SessionIDFromContext(ctx context.Context) uint {
+	sessionID, _ := ctx.Value(sessionIDKey).(uint)
+	return sessionID
+}
+
+// WithRequestID stores the ID that correlates a request with its response and logs
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestIDFromContext returns the request ID, or "" outside the request ID middleware
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"gofamtree/config"
+	"log"
+	"strings"
+)
+
+// encryptedFieldPrefix marks values written by EncryptField:
+// enc:v1:<key id>:<base64 nonce + AES-GCM ciphertext>
+const encryptedFieldPrefix = "enc:v1:"
+
+// plainFieldPrefix escapes plain text that itself starts with "enc:" while
+// encryption is disabled, so it is never read back as a ciphertext
+const plainFieldPrefix = "enc:plain:"
+
+var (
+	ErrUnknownFieldKey       = errors.New("encrypted with an unknown key")
+	ErrInvalidEncryptedField = errors.New("invalid encrypted value")
+)
+
+// FieldKeys encrypts and decrypts sensitive columns; nil when encryption is disabled
+var FieldKeys *FieldKeyring
+
+// FieldKeyring holds the AES-GCM keys from ENCRYPTION_KEYS. Active encrypts,
+// every key decrypts.
+type FieldKeyring struct {
+	Active string
+	aeads  map[string]cipher.AEAD
+}
+
+func InitFieldEncryption() {
+	if len(config.EncryptionKeys) == 0 {
+		return
+	}
+	keyring, err := NewFieldKeyring(config.EncryptionKeys)
+	if err != nil {
+		log.Fatal("Failed to load ENCRYPTION_KEYS:", err)
+	}
+	FieldKeys = keyring
+}
+
+// NewFieldKeyring builds a keyring whose first key is the active one
+func NewFieldKeyring(keys []config.EncryptionKey) (*FieldKeyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no encryption keys")
+	}
+	keyring := &FieldKeyring{Active: keys[0].ID, aeads: map[string]cipher.AEAD{}}
+	for _, key := range keys {
+		block, err := aes.NewCipher(key.Key)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", key.ID, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", key.ID, err)
+		}
+		keyring.aeads[key.ID] = aead
+	}
+	return keyring, nil
+}
+
+// EncryptField encrypts a value with the active key. The column name is bound
+// in as additional data, so a ciphertext cannot be moved to another column.
+// Empty values stay empty, and values pass through unchanged while encryption
+// is disabled, unless they start with "enc:" and need escaping.
+func EncryptField(plaintext, column string) (string, error) {
+	if FieldKeys == nil && strings.HasPrefix(plaintext, "enc:") {
+		return plainFieldPrefix + plaintext, nil
+	}
+	if FieldKeys == nil || plaintext == "" {
+		return plaintext, nil
+	}
+	aead := FieldKeys.aeads[FieldKeys.Active]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(column))
+	return encryptedFieldPrefix + FieldKeys.Active + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptField reverses EncryptField. Values without the prefix were written
+// before encryption was enabled and are returned as they are.
+func DecryptField(value, column string) (string, error) {
+	if plaintext, escaped := strings.CutPrefix(value, plainFieldPrefix); escaped {
+		return plaintext, nil
+	}
+	keyID, encoded, encrypted := splitEncryptedField(value)
+	if !encrypted {
+		return value, nil
+	}
+	if FieldKeys == nil {
+		return "", fmt.Errorf("%w %q: ENCRYPTION_KEYS is not set", ErrUnknownFieldKey, keyID)
+	}
+	aead, ok := FieldKeys.aeads[keyID]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownFieldKey, keyID)
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrInvalidEncryptedField
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(column))
+	if err != nil {
+		return "", ErrInvalidEncryptedField
+	}
+	return string(plaintext), nil
+}
+
+// FieldNeedsReencryption reports whether a stored value is plain text or was
+// encrypted with a key other than the active one
+func FieldNeedsReencryption(value string) bool {
+	if FieldKeys == nil || value == "" {
+		return false
+	}
+	keyID, _, encrypted := splitEncryptedField(value)
+	return !encrypted || keyID != FieldKeys.Active
+}
+
+func splitEncryptedField(value string) (keyID, encoded string, ok bool) {
+	rest := strings.TrimPrefix(value, encryptedFieldPrefix)
+	if rest == value {
+		return "", "", false
+	}
+	return strings.Cut(rest, ":")
+}
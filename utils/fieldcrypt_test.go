@@ -0,0 +1,190 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"gofamtree/config"
+	"strings"
+	"testing"
+)
+
+func testKey(id string, fill byte) config.EncryptionKey {
+	return config.EncryptionKey{ID: id, Key: bytes.Repeat([]byte{fill}, 32)}
+}
+
+// withFieldKeys installs a keyring built from keys, the first one active; no
+// keys disables encryption
+func withFieldKeys(t *testing.T, keys ...config.EncryptionKey) {
+	t.Helper()
+	previous := FieldKeys
+	t.Cleanup(func() { FieldKeys = previous })
+
+	FieldKeys = nil
+	if len(keys) == 0 {
+		return
+	}
+	keyring, err := NewFieldKeyring(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	FieldKeys = keyring
+}
+
+func TestFieldEncryptionRoundTrip(t *testing.T) {
+	withFieldKeys(t, testKey("k1", 1))
+
+	encrypted, err := EncryptField("jane@example.com", "contact")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(encrypted, "enc:v1:k1:") || strings.Contains(encrypted, "jane") {
+		t.Fatalf("encrypted value %q is not an enc:v1 value of key k1", encrypted)
+	}
+	if again, _ := EncryptField("jane@example.com", "contact"); again == encrypted {
+		t.Error("two encryptions of one value are equal, so the nonce is not random")
+	}
+
+	decrypted, err := DecryptField(encrypted, "contact")
+	if err != nil || decrypted != "jane@example.com" {
+		t.Fatalf("DecryptField = %q, %v; want the original value", decrypted, err)
+	}
+
+	if empty, _ := EncryptField("", "contact"); empty != "" {
+		t.Errorf("an empty value was encrypted to %q", empty)
+	}
+	if plain, err := DecryptField("written before encryption", "contact"); err != nil || plain != "written before encryption" {
+		t.Errorf("a plain-text value came back as %q, %v", plain, err)
+	}
+}
+
+func TestDecryptFieldRejectsTampering(t *testing.T) {
+	withFieldKeys(t, testKey("k1", 1))
+	encrypted, err := EncryptField("born in Leeds", "description")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := []byte(encrypted)
+	tampered[len(tampered)-1] ^= 1
+
+	tests := []struct {
+		name, value, column string
+	}{
+		{"other column", encrypted, "contact"},
+		{"flipped bit", string(tampered), "description"},
+		{"not base64", "enc:v1:k1:***", "description"},
+		{"too short", "enc:v1:k1:AAAA", "description"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecryptField(tt.value, tt.column); !errors.Is(err, ErrInvalidEncryptedField) {
+				t.Errorf("DecryptField = %v, want ErrInvalidEncryptedField", err)
+			}
+		})
+	}
+}
+
+func TestFieldKeyRotation(t *testing.T) {
+	withFieldKeys(t, testKey("k1", 1))
+	old, err := EncryptField("jane@example.com", "contact")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// k2 becomes active while k1 still decrypts what it wrote
+	withFieldKeys(t, testKey("k2", 2), testKey("k1", 1))
+	if decrypted, err := DecryptField(old, "contact"); err != nil || decrypted != "jane@example.com" {
+		t.Fatalf("old-key value decrypted to %q, %v", decrypted, err)
+	}
+	if !FieldNeedsReencryption(old) {
+		t.Error("a value of the old key is not marked for re-encryption")
+	}
+	if !FieldNeedsReencryption("plain text") {
+		t.Error("a plain-text value is not marked for re-encryption")
+	}
+	if FieldNeedsReencryption("") {
+		t.Error("an empty value is marked for re-encryption")
+	}
+
+	rotated, err := EncryptField("jane@example.com", "contact")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(rotated, "enc:v1:k2:") || FieldNeedsReencryption(rotated) {
+		t.Errorf("re-encrypted value %q does not use the active key", rotated)
+	}
+
+	// Once k1 is retired its values can no longer be read
+	withFieldKeys(t, testKey("k2", 2))
+	if _, err := DecryptField(old, "contact"); !errors.Is(err, ErrUnknownFieldKey) {
+		t.Errorf("DecryptField with a retired key = %v, want ErrUnknownFieldKey", err)
+	}
+	if decrypted, err := DecryptField(rotated, "contact"); err != nil || decrypted != "jane@example.com" {
+		t.Errorf("re-encrypted value decrypted to %q, %v", decrypted, err)
+	}
+}
+
+func TestFieldEncryptionDisabled(t *testing.T) {
+	withFieldKeys(t, testKey("k1", 1))
+	encrypted, err := EncryptField("jane@example.com", "contact")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withFieldKeys(t)
+	if value, _ := EncryptField("jane@example.com", "contact"); value != "jane@example.com" {
+		t.Errorf("EncryptField without keys = %q, want the value unchanged", value)
+	}
+	if _, err := DecryptField(encrypted, "contact"); !errors.Is(err, ErrUnknownFieldKey) {
+		t.Errorf("DecryptField without keys = %v, want ErrUnknownFieldKey", err)
+	}
+	if FieldNeedsReencryption("plain text") {
+		t.Error("values are marked for re-encryption while encryption is disabled")
+	}
+}
+
+func TestFieldEncryptionEscapesPrefix(t *testing.T) {
+	values := []string{"enc:v1:k1:not a ciphertext", "enc:plain:x", "enc:", "plain text"}
+
+	withFieldKeys(t)
+	for _, value := range values {
+		stored, err := EncryptField(value, "contact")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decrypted, err := DecryptField(stored, "contact"); err != nil || decrypted != value {
+			t.Errorf("%q stored without keys as %q read back as %q, %v", value, stored, decrypted, err)
+		}
+
+		// Turning encryption on later still reads the escaped value
+		withFieldKeys(t, testKey("k1", 1))
+		if decrypted, err := DecryptField(stored, "contact"); err != nil || decrypted != value {
+			t.Errorf("%q stored without keys read back with keys as %q, %v", value, decrypted, err)
+		}
+		if stored != value && !FieldNeedsReencryption(stored) {
+			t.Errorf("escaped value %q is not marked for encryption", stored)
+		}
+
+		encrypted, err := EncryptField(value, "contact")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decrypted, err := DecryptField(encrypted, "contact"); err != nil || decrypted != value {
+			t.Errorf("%q encrypted read back as %q, %v", value, decrypted, err)
+		}
+		withFieldKeys(t)
+	}
+}
+
+func TestNewFieldKeyring(t *testing.T) {
+	if _, err := NewFieldKeyring(nil); err == nil {
+		t.Error("a keyring without keys was accepted")
+	}
+	if _, err := NewFieldKeyring([]config.EncryptionKey{{ID: "short", Key: []byte("too short")}}); err == nil {
+		t.Error("a key of the wrong length was accepted")
+	}
+	keyring, err := NewFieldKeyring([]config.EncryptionKey{testKey("new", 2), testKey("old", 1)})
+	if err != nil || keyring.Active != "new" {
+		t.Errorf("keyring = %+v, %v; want the first key active", keyring, err)
+	}
+}
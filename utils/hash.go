@@ -1,13 +1,156 @@
 package utils
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
 
-func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordHasher hashes passwords into self-describing strings. The algorithm
+// and its parameters are encoded in the hash, so hashes made with older
+// settings can still be verified and recognised as outdated.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// Handles reports whether the encoded hash was produced by this algorithm
+	Handles(encoded string) bool
+	Verify(password, encoded string) bool
+	// Outdated reports whether a hash of this algorithm uses other parameters
+	Outdated(encoded string) bool
+}
+
+var ErrInvalidHash = errors.New("invalid password hash")
+
+// Hasher is used for new hashes. Every known hasher is tried when verifying.
+var Hasher PasswordHasher = BcryptHasher{Cost: bcrypt.DefaultCost}
+
+// BcryptHasher produces standard $2a$ hashes; the cost is part of the hash
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h BcryptHasher) Hash(password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
 	return string(bytes), err
 }
 
+func (BcryptHasher) Handles(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func (BcryptHasher) Verify(password, encoded string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)) == nil
+}
+
+func (h BcryptHasher) Outdated(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != h.Cost
+}
+
+// Argon2idHasher produces PHC strings:
+// $argon2id$v=19$m=<memory KiB>,t=<iterations>,p=<parallelism>$<salt>$<key>
+type Argon2idHasher struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+func (h Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (Argon2idHasher) Handles(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+func (Argon2idHasher) Verify(password, encoded string) bool {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false
+	}
+	candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(candidate, key) == 1
+}
+
+func (h Argon2idHasher) Outdated(encoded string) bool {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params.Memory != h.Memory || params.Iterations != h.Iterations || params.Parallelism != h.Parallelism ||
+		uint32(len(salt)) != h.SaltLength || uint32(len(key)) != h.KeyLength
+}
+
+func decodeArgon2id(encoded string) (Argon2idHasher, []byte, []byte, error) {
+	var params Argon2idHasher
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrInvalidHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrInvalidHash
+	}
+	return params, salt, key, nil
+}
+
+// hasherFor finds the hasher that understands an encoded hash
+func hasherFor(encoded string) PasswordHasher {
+	for _, hasher := range []PasswordHasher{Hasher, BcryptHasher{}, Argon2idHasher{}} {
+		if hasher.Handles(encoded) {
+			return hasher
+		}
+	}
+	return nil
+}
+
+func HashPassword(password string) (string, error) {
+	return Hasher.Hash(password)
+}
+
 func CheckPasswordHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+	hasher := hasherFor(hash)
+	return hasher != nil && hasher.Verify(password, hash)
+}
+
+// PasswordNeedsRehash reports whether a stored hash was made with another
+// algorithm or other parameters than the configured Hasher
+func PasswordNeedsRehash(hash string) bool {
+	return !Hasher.Handles(hash) || Hasher.Outdated(hash)
+}
+
+// RehashPassword returns a new hash of a verified password when its stored hash
+// needs upgrading, and false when the stored hash is current
+func RehashPassword(password, hash string) (string, bool) {
+	if !PasswordNeedsRehash(hash) {
+		return "", false
+	}
+	rehashed, err := HashPassword(password)
+	return rehashed, err == nil
 }
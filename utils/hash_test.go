@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+// testArgon2id keeps the tests fast; production parameters come from config
+var testArgon2id = Argon2idHasher{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func withHasher(t *testing.T, hasher PasswordHasher) {
+	t.Helper()
+	previous := Hasher
+	Hasher = hasher
+	t.Cleanup(func() { Hasher = previous })
+}
+
+func TestPasswordHashers(t *testing.T) {
+	for _, hasher := range []PasswordHasher{testArgon2id, BcryptHasher{Cost: 4}} {
+		withHasher(t, hasher)
+
+		hash, err := HashPassword("correct horse")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !hasher.Handles(hash) {
+			t.Errorf("%T does not recognise its own hash %s", hasher, hash)
+		}
+		if !CheckPasswordHash("correct horse", hash) {
+			t.Errorf("%T: the right password was refused", hasher)
+		}
+		if CheckPasswordHash("wrong horse", hash) {
+			t.Errorf("%T: a wrong password was accepted", hasher)
+		}
+		if again, _ := HashPassword("correct horse"); again == hash {
+			t.Errorf("%T: two hashes of one password are equal, so the salt is not random", hasher)
+		}
+	}
+}
+
+func TestArgon2idHashFormat(t *testing.T) {
+	hash, err := testArgon2id.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("hash %s does not carry its parameters", hash)
+	}
+}
+
+func TestCheckPasswordHashRejectsMalformedHashes(t *testing.T) {
+	for _, hash := range []string{
+		"",
+		"plaintext",
+		"$argon2id$v=19$m=1024,t=1,p=1$salt",
+		"$argon2id$v=18$m=1024,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5",
+		"$argon2id$v=19$m=x,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$",
+		"$2a$04$tooshort",
+	} {
+		if CheckPasswordHash("secret", hash) {
+			t.Errorf("CheckPasswordHash accepted %q", hash)
+		}
+	}
+}
+
+func TestRehashPassword(t *testing.T) {
+	withHasher(t, testArgon2id)
+	bcryptHash, _ := BcryptHasher{Cost: 4}.Hash("secret")
+	currentHash, _ := testArgon2id.Hash("secret")
+	weakerHash, _ := Argon2idHasher{Memory: 512, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}.Hash("secret")
+
+	tests := []struct {
+		name   string
+		hash   string
+		rehash bool
+	}{
+		{"current parameters", currentHash, false},
+		{"other algorithm", bcryptHash, true},
+		{"other parameters", weakerHash, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rehashed, ok := RehashPassword("secret", tt.hash)
+			if ok != tt.rehash {
+				t.Fatalf("RehashPassword rehashed = %v, want %v", ok, tt.rehash)
+			}
+			if !ok {
+				return
+			}
+			if PasswordNeedsRehash(rehashed) {
+				t.Errorf("the new hash %s is outdated too", rehashed)
+			}
+			if !CheckPasswordHash("secret", rehashed) {
+				t.Error("the new hash does not verify the password")
+			}
+		})
+	}
+
+	// Hashes of the previous algorithm still verify after the switch
+	if !CheckPasswordHash("secret", bcryptHash) {
+		t.Error("a bcrypt hash no longer verifies with argon2id configured")
+	}
+}
@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"fmt"
+	"gofamtree/config"
+	"log"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// MailSender delivers outgoing mail. The implementation is picked by MAIL_DRIVER.
+type MailSender interface {
+	Send(msg Mail) error
+}
+
+// SMTPSender delivers mail through an SMTP server, e.g. a local MailHog instance
+type SMTPSender struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
+}
+
+// FileSender writes each message as an .eml file into an outbox directory
+type FileSender struct {
+	Dir  string
+	From string
+}
+
+// LogSender writes messages to the server log, for development
+type LogSender struct{}
+
+// Mailer is the sender used by the handlers. It can be replaced, e.g. in tools
+// that need to capture outgoing mail.
+var Mailer MailSender
+
+// InitMailer selects the mail sender configured in config.MailDriver
+func InitMailer() {
+	switch config.MailDriver {
+	case "smtp":
+		Mailer = SMTPSender{
+			Addr:     config.SMTPAddr,
+			From:     config.MailFrom,
+			Username: config.SMTPUsername,
+			Password: config.SMTPPassword,
+		}
+	case "file":
+		Mailer = FileSender{Dir: config.MailOutboxDir, From: config.MailFrom}
+	default:
+		Mailer = LogSender{}
+	}
+}
+
+func (s SMTPSender) Send(msg Mail) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		host := s.Addr
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+	return smtp.SendMail(s.Addr, auth, s.From, []string{msg.To}, formatMail(s.From, msg))
+}
+
+func (s FileSender) Send(msg Mail) error {
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return err
+	}
+
+	// One file per message, named so the outbox sorts chronologically
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), sanitizeFileName(msg.To))
+	return os.WriteFile(filepath.Join(s.Dir, name), formatMail(s.From, msg), 0o600)
+}
+
+func (LogSender) Send(msg Mail) error {
+	log.Printf("Mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+func formatMail(from string, msg Mail) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+func sanitizeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '@' {
+			return r
+		}
+		return '_'
+	}, s)
+}
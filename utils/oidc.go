@@ -0,0 +1,326 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gofamtree/config"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrInvalidIDToken = errors.New("invalid ID token")
+
+// OIDC is the client for the configured identity provider, nil when SSO is disabled
+var OIDC *OIDCClient
+
+// oidcClockSkew tolerates small clock differences with the provider
+const oidcClockSkew = time.Minute
+
+// OIDCClient runs the authorization-code flow against one OpenID provider.
+// Endpoints come from the provider's discovery document and signing keys from
+// its JWKS; both are fetched on first use and cached.
+type OIDCClient struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	HTTP         *http.Client
+
+	mu          sync.Mutex
+	discovery   *oidcDiscovery
+	keys        map[string]*rsa.PublicKey
+	keysFetched time.Time
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func InitOIDCClient() {
+	if !config.OIDCEnabled() {
+		return
+	}
+	OIDC = &OIDCClient{
+		Issuer:       config.OIDCIssuer,
+		ClientID:     config.OIDCClientID,
+		ClientSecret: config.OIDCClientSecret,
+		RedirectURL:  config.OIDCRedirectURL,
+		Scopes:       config.OIDCScopes,
+		HTTP:         &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// NewPKCEVerifier returns a PKCE code verifier and its S256 challenge
+func NewPKCEVerifier() (string, string, error) {
+	verifier, err := RandomToken()
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+func (c *OIDCClient) getDiscovery() (*oidcDiscovery, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.discovery != nil {
+		return c.discovery, nil
+	}
+
+	var discovery oidcDiscovery
+	if err := c.getJSON(c.Issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("OIDC discovery: %w", err)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != c.Issuer {
+		return nil, fmt.Errorf("OIDC discovery: issuer %q does not match %q", discovery.Issuer, c.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, errors.New("OIDC discovery: document is missing endpoints")
+	}
+	c.discovery = &discovery
+	return c.discovery, nil
+}
+
+// AuthCodeURL builds the provider URL the browser is sent to
+func (c *OIDCClient) AuthCodeURL(state, nonce, codeChallenge string) (string, error) {
+	discovery, err := c.getDiscovery()
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.ClientID},
+		"redirect_uri":          {c.RedirectURL},
+		"scope":                 {strings.Join(c.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Exchange trades an authorization code for the provider's ID token
+func (c *OIDCClient) Exchange(code, codeVerifier string) (string, error) {
+	discovery, err := c.getDiscovery()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.RedirectURL},
+		"client_id":     {c.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+	if c.ClientSecret != "" {
+		form.Set("client_secret", c.ClientSecret)
+	}
+
+	resp, err := c.HTTP.PostForm(discovery.TokenEndpoint, form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("OIDC token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || result.Error != "" {
+		return "", fmt.Errorf("OIDC token request failed: %s %s", result.Error, result.ErrorDescription)
+	}
+	if result.IDToken == "" {
+		return "", errors.New("OIDC token response has no id_token")
+	}
+	return result.IDToken, nil
+}
+
+// VerifyIDToken checks an RS256 ID token's signature, issuer, audience, expiry
+// and nonce, and returns its claims
+func (c *OIDCClient) VerifyIDToken(idToken, nonce string) (map[string]interface{}, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidIDToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "RS256" {
+		return nil, ErrInvalidIDToken
+	}
+
+	key, err := c.signingKey(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidIDToken
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, ErrInvalidIDToken
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrInvalidIDToken
+	}
+
+	if strings.TrimSuffix(ClaimString(claims, "iss"), "/") != c.Issuer {
+		return nil, fmt.Errorf("%w: wrong issuer", ErrInvalidIDToken)
+	}
+	if !c.audienceMatches(claims) {
+		return nil, fmt.Errorf("%w: wrong audience", ErrInvalidIDToken)
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok || time.Now().Add(-oidcClockSkew).Unix() >= int64(exp) {
+		return nil, fmt.Errorf("%w: expired", ErrInvalidIDToken)
+	}
+	if ClaimString(claims, "nonce") != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	if ClaimString(claims, "sub") == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+	return claims, nil
+}
+
+func (c *OIDCClient) audienceMatches(claims map[string]interface{}) bool {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return aud == c.ClientID
+	case []interface{}:
+		found := false
+		for _, value := range aud {
+			if value == c.ClientID {
+				found = true
+			}
+		}
+		// With several audiences the token must have been issued to us
+		if azp := ClaimString(claims, "azp"); found && len(aud) > 1 {
+			return azp == c.ClientID
+		}
+		return found
+	}
+	return false
+}
+
+// signingKey returns the provider key with the given ID, refreshing the JWKS
+// (at most once a minute) when the provider has rotated its keys
+func (c *OIDCClient) signingKey(kid string) (*rsa.PublicKey, error) {
+	discovery, err := c.getDiscovery()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if key := c.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	if time.Since(c.keysFetched) < time.Minute {
+		return nil, fmt.Errorf("%w: unknown signing key", ErrInvalidIDToken)
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := c.getJSON(discovery.JWKSURI, &jwks); err != nil {
+		return nil, fmt.Errorf("OIDC JWKS: %w", err)
+	}
+
+	c.keys = map[string]*rsa.PublicKey{}
+	c.keysFetched = time.Now()
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+		e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		c.keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	if key := c.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unknown signing key", ErrInvalidIDToken)
+}
+
+// lookupKey finds a cached key. Tokens without a key ID are accepted only
+// when the provider publishes a single key.
+func (c *OIDCClient) lookupKey(kid string) *rsa.PublicKey {
+	if kid == "" && len(c.keys) == 1 {
+		for _, key := range c.keys {
+			return key
+		}
+	}
+	return c.keys[kid]
+}
+
+func (c *OIDCClient) getJSON(url string, v interface{}) error {
+	resp, err := c.HTTP.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// ClaimString returns a string claim, or "" when it is missing or not a string
+func ClaimString(claims map[string]interface{}, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// ClaimBool accepts booleans and the "true" string some providers send
+func ClaimBool(claims map[string]interface{}, name string) bool {
+	switch value := claims[name].(type) {
+	case bool:
+		return value
+	case string:
+		return value == "true"
+	}
+	return false
+}
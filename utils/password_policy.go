@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gofamtree/config"
+	"log"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// bcrypt ignores everything after the first 72 bytes
+const bcryptMaxPasswordBytes = 72
+
+// breachedPasswords holds upper-case SHA-1 hex digests of known-breached passwords
+var breachedPasswords = map[string]struct{}{}
+
+// InitPasswordHasher picks the hasher for new hashes and loads the breached list
+func InitPasswordHasher() {
+	switch config.PasswordHashAlgorithm {
+	case "bcrypt":
+		Hasher = BcryptHasher{Cost: config.BcryptCost}
+	default:
+		Hasher = Argon2idHasher{
+			Memory:      uint32(config.Argon2Memory),
+			Iterations:  uint32(config.Argon2Iterations),
+			Parallelism: uint8(config.Argon2Parallelism),
+			SaltLength:  16,
+			KeyLength:   32,
+		}
+	}
+
+	if config.PasswordBreachedList != "" {
+		count, err := loadBreachedPasswords(config.PasswordBreachedList)
+		if err != nil {
+			log.Fatal("Failed to load PASSWORD_BREACHED_LIST:", err)
+		}
+		log.Printf("Loaded %d breached passwords from %s", count, config.PasswordBreachedList)
+	}
+}
+
+// loadBreachedPasswords reads one entry per line. An entry is either a plain
+// password or, as in Have I Been Pwned downloads, a SHA-1 digest with an
+// optional ":count" suffix.
+func loadBreachedPasswords(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		digest, _, _ := strings.Cut(line, ":")
+		if len(digest) == sha1.Size*2 {
+			if _, err := hex.DecodeString(digest); err == nil {
+				breachedPasswords[strings.ToUpper(digest)] = struct{}{}
+				continue
+			}
+		}
+		breachedPasswords[passwordDigest(line)] = struct{}{}
+	}
+	return len(breachedPasswords), scanner.Err()
+}
+
+func passwordDigest(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// CheckPasswordPolicy returns a message-ready error when a new password is not acceptable
+func CheckPasswordPolicy(password, username string) error {
+	length := utf8.RuneCountInString(password)
+	if length < config.PasswordMinLength {
+		return fmt.Errorf("Password must be at least %d characters", config.PasswordMinLength)
+	}
+	if length > config.PasswordMaxLength {
+		return fmt.Errorf("Password must be at most %d characters", config.PasswordMaxLength)
+	}
+	if _, ok := Hasher.(BcryptHasher); ok && len(password) > bcryptMaxPasswordBytes {
+		return fmt.Errorf("Password must be at most %d bytes", bcryptMaxPasswordBytes)
+	}
+	if username != "" && strings.EqualFold(password, username) {
+		return errors.New("Password must not match the username")
+	}
+	if _, ok := breachedPasswords[passwordDigest(password)]; ok {
+		return errors.New("Password appears in a list of breached passwords, choose another")
+	}
+	return nil
+}
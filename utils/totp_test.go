@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 test vectors, "12345678901234567890"
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+// rfc6238Vectors are the SHA-1 vectors of RFC 6238 appendix B, cut to six digits
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestTOTPCode(t *testing.T) {
+	for _, tt := range rfc6238Vectors {
+		got, err := TOTPCode(rfc6238Secret, tt.unix/totpPeriod)
+		if err != nil {
+			t.Fatalf("TOTPCode(%d): %v", tt.unix, err)
+		}
+		if got != tt.code {
+			t.Errorf("TOTPCode(%d) = %s, want %s", tt.unix, got, tt.code)
+		}
+	}
+
+	// Secrets are accepted in lower case, as some apps show them
+	if got, _ := TOTPCode(strings.ToLower(rfc6238Secret), 1); got != "287082" {
+		t.Errorf("lower-case secret gave %s", got)
+	}
+	if _, err := TOTPCode("not base32!", 1); err == nil {
+		t.Error("TOTPCode accepted an invalid secret")
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	counter := now.Unix() / totpPeriod
+
+	tests := []struct {
+		name  string
+		code  string
+		want  int64
+		valid bool
+	}{
+		{"current step", "050471", counter, true},
+		{"with spaces", " 050 471 ", counter, true},
+		{"previous step", codeAt(t, counter-1), counter - 1, true},
+		{"next step", codeAt(t, counter+1), counter + 1, true},
+		{"two steps back", codeAt(t, counter-2), 0, false},
+		{"wrong code", "000000", 0, false},
+		{"too short", "05047", 0, false},
+		{"too long", "0504711", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ValidateTOTP(rfc6238Secret, tt.code, now)
+			if ok != tt.valid || got != tt.want {
+				t.Errorf("ValidateTOTP(%q) = %d, %v, want %d, %v", tt.code, got, ok, tt.want, tt.valid)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPAfterRefusesReplays(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	counter := now.Unix() / totpPeriod
+
+	accepted, ok := ValidateTOTPAfter(rfc6238Secret, "050471", now, 0)
+	if !ok || accepted != counter {
+		t.Fatalf("first use = %d, %v, want %d, true", accepted, ok, counter)
+	}
+	if _, ok := ValidateTOTPAfter(rfc6238Secret, "050471", now, accepted); ok {
+		t.Error("the same code was accepted twice")
+	}
+	if _, ok := ValidateTOTPAfter(rfc6238Secret, codeAt(t, counter-1), now, accepted); ok {
+		t.Error("an older code was accepted after a newer one")
+	}
+	if got, ok := ValidateTOTPAfter(rfc6238Secret, codeAt(t, counter+1), now, accepted); !ok || got != counter+1 {
+		t.Errorf("the next step = %d, %v, want %d, true", got, ok, counter+1)
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("GoFamTree", "admin", "JBSWY3DPEHPK3PXP")
+	for _, part := range []string{"otpauth://totp/GoFamTree:admin?", "secret=JBSWY3DPEHPK3PXP", "issuer=GoFamTree", "digits=6", "period=30"} {
+		if !strings.Contains(uri, part) {
+			t.Errorf("URI %s lacks %s", uri, part)
+		}
+	}
+}
+
+func TestNewTOTPSecret(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := TOTPCode(secret, 0); err != nil {
+		t.Errorf("new secret %q does not decode: %v", secret, err)
+	}
+}
+
+func codeAt(t *testing.T, counter int64) string {
+	t.Helper()
+	code, err := TOTPCode(rfc6238Secret, counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}